@@ -4,44 +4,75 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
-	"time"
+	"syscall"
 
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
+	analyzer "github.com/rgehrsitz/AutoDoc/internal/analysis"
 	"github.com/rgehrsitz/AutoDoc/internal/collector"
-	"github.com/rgehrsitz/AutoDoc/internal/docs"
+	"github.com/rgehrsitz/AutoDoc/internal/generator"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/internal/pipeline"
+	"github.com/rgehrsitz/AutoDoc/internal/progress"
+	"github.com/rgehrsitz/AutoDoc/internal/prompts"
 	"github.com/rgehrsitz/AutoDoc/internal/storage"
 	"github.com/rgehrsitz/AutoDoc/pkg/config"
+	"github.com/rgehrsitz/AutoDoc/web/handlers"
 )
 
-// generateID creates a unique ID based on the provided path
-func generateID(path string) string {
-	hash := sha256.Sum256([]byte(path))
-	return hex.EncodeToString(hash[:])[:16] // Uses first 16 characters of the hash
-}
-
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*config.Config, error) {
 	return config.LoadConfig()
 }
 
 func main() {
-	// Print startup message
-	fmt.Println("Starting AutoDoc...")
+	// "autodoc retry-failed [flags]" re-documents only the failed files of a run
+	retryFailed := len(os.Args) > 1 && os.Args[1] == "retry-failed"
+	args := os.Args[1:]
+	if retryFailed {
+		args = os.Args[2:]
+	}
 
 	// Define CLI flags
 	repoURL := flag.String("repo", "", "URL of the repository to document")
 	path := flag.String("path", "", "Path to the local repository to document")
-	extensions := flag.String("extensions", ".js,.ts,.go,.rs,.py,.java", "Comma-separated list of file extensions to include")
-	flag.Parse()
+	extensions := flag.String("extensions", ".js,.ts,.go,.rs,.py,.java", "Comma-separated list of file extensions to include, along with the well-known files of their languages (empty for every known language)")
+	noCache := flag.Bool("no-cache", false, "Ignore cached LLM responses (fresh responses are still cached)")
+	clearCache := flag.Bool("clear-cache", false, "Remove all cached LLM responses before running")
+	maxCost := flag.Float64("max-cost", 0, "Stop scheduling new files once LLM spend reaches this many US dollars (0 for no limit)")
+	outdatedOnly := flag.Bool("outdated-only", false, "Only re-document files whose stored page was built with a different prompt version")
+	maxTokens := flag.Int64("max-tokens", 0, "Stop scheduling new files once this many LLM tokens have been used (0 for no limit)")
+	resume := flag.String("resume", "", "ID of an interrupted run to resume; finished files are skipped and failed ones retried (with retry-failed, defaults to the latest run with failures)")
+	storageDir := flag.String("storage", "", "Directory of the documentation database (defaults to <repo>/storage)")
+	renderPartial := flag.Bool("render-partial", false, "Generate the site from the files documented so far when the run is interrupted")
+	output := flag.String("output", progress.FormatText, "Progress output: \"text\" for a progress bar or log lines, \"json\" for JSON lines on stdout")
+	continueOnError := flag.Bool("continue-on-error", false, "Keep documenting the remaining files when one fails; failed files get a placeholder page")
+	ref := flag.String("ref", "", "Branch, tag or commit to document with -repo (defaults to the default branch)")
+	depth := flag.Int("depth", 0, "Number of commits to fetch with -repo (0 for the full history)")
+	sparse := flag.String("sparse", "", "Comma-separated directories to check out with -repo (defaults to every file)")
+	submodules := flag.Bool("submodules", false, "Check out submodules recursively with -repo")
+	wikiDir := flag.String("wiki", "", "Directory to also generate an HTML wiki of the stored pages into after a successful run")
+	flag.CommandLine.Parse(args)
+
+	// Report progress on stdout; other messages move to stderr when it carries JSON
+	reporter, err := progress.NewReporter(*output, os.Stdout)
+	if err != nil {
+		log.Fatalf("Invalid -output flag: %v", err)
+	}
+	tracker := progress.NewTracker(reporter)
+	console := io.Writer(os.Stdout)
+	if *output == progress.FormatJSON {
+		console = os.Stderr
+	}
+
+	// Print startup message
+	fmt.Fprintln(console, "Starting AutoDoc...")
 
 	// Validate flags
 	if *repoURL == "" && *path == "" {
@@ -57,135 +88,295 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Initialize OpenAI client with options
-	client := openai.NewClient(
-		option.WithAPIKey(config.OpenAIKey),
-	)
-	fmt.Println("OpenAI client initialized.")
+	// Initialize the analysis backend from configuration
+	var fileAnalyzer analyzer.FileAnalyzer
+	var llmAnalyzer *analyzer.Analyzer
+	var provider llm.Provider
+	var concurrency *generator.AdaptiveLimiter
+	ledger := llm.NewLedger(llm.NewPriceTable(config.LLM.Prices), *maxTokens, *maxCost)
+	if config.LLM.Offline() {
+		fileAnalyzer = analyzer.NewStaticAnalyzer()
+		fmt.Fprintln(console, "Offline mode: using static analysis, no LLM calls will be made.")
+	} else {
+		provider, err = llm.NewProvider(config.LLM)
+		if err != nil {
+			log.Fatalf("Failed to initialize LLM provider: %v", err)
+		}
+		concurrency = generator.NewAdaptiveLimiterFromConfig(config.LLM)
+		throttled := generator.NewThrottledProvider(provider,
+			generator.NewRateLimiterFromConfig(config.LLM), generator.DefaultRetryConfig())
+		throttled.SetConcurrency(concurrency)
+		provider = llm.NewMeteredProvider(throttled, ledger)
+		library, err := prompts.Load(config.LLM.PromptsDir)
+		if err != nil {
+			log.Fatalf("Failed to load prompt templates: %v", err)
+		}
+		llmAnalyzer = analyzer.NewAnalyzer(provider)
+		llmAnalyzer.SetPrompts(library)
+		llmAnalyzer.SetRepairAttempts(config.LLM.RepairAttempts)
+		if config.LLM.ContextTokens > 0 {
+			llmAnalyzer.SetContextWindow(config.LLM.ContextTokens)
+		}
+		fileAnalyzer = llmAnalyzer
+		fmt.Fprintf(console, "LLM provider initialized (%s, model %s, prompts %s).\n", provider.Name(), provider.Model(), library.Version())
+	}
 
-	ctx := context.Background()
+	// Cancel the run on SIGINT or SIGTERM; a second signal exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
-	// Initialize Collector
-	collector := collector.NewCollector()
+	// Initialize Collector and the filter applied to the files it finds
+	filter := collector.NewFilter(config.Files.Include, config.Files.Exclude)
+	filter.SetMaxSize(config.Files.MaxFileSize)
+	filter.SetIncludeGenerated(config.Files.IncludeGenerated)
+	fsCollector := collector.NewCollector()
 
-	var repoPath string
+	// Pages and the database go into the repository, or the current directory
+	// for a clone, which is removed when the run finishes
+	var repoPath, outputDir string
+	cleanup := func() {}
 	if *path != "" {
 		// Use the provided local path
 		repoPath = *path
-		fmt.Printf("Using local repository path: %s\n", repoPath)
+		outputDir = repoPath
+		fmt.Fprintf(console, "Using local repository path: %s\n", repoPath)
 	} else {
 		// Clone repository
-		repoPath, err = collector.Clone(ctx, *repoURL)
+		var sparsePaths []string
+		for _, dir := range strings.Split(*sparse, ",") {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				sparsePaths = append(sparsePaths, dir)
+			}
+		}
+		fsCollector.SetCloneOptions(collector.CloneOptions{Ref: *ref, Depth: *depth, SparsePaths: sparsePaths, Submodules: *submodules})
+		repoPath, err = fsCollector.Clone(ctx, *repoURL)
 		if err != nil {
 			log.Fatalf("Failed to clone repository: %v", err)
 		}
-		fmt.Printf("Repository cloned to %s\n", repoPath)
+		cleanup = func() {
+			if err := os.RemoveAll(repoPath); err != nil {
+				log.Printf("Failed to remove clone %s: %v", repoPath, err)
+			}
+		}
+		outputDir, err = os.Getwd()
+		if err != nil {
+			cleanup()
+			log.Fatalf("Failed to get working directory: %v", err)
+		}
+		fmt.Fprintf(console, "Repository cloned to %s\n", repoPath)
 	}
+	defer cleanup()
 
-	// Parse extensions
-	extList := strings.Split(*extensions, ",")
-	extMap := make(map[string]bool)
-	for _, ext := range extList {
-		trimmedExt := strings.TrimSpace(ext)
-		if !strings.HasPrefix(trimmedExt, ".") {
-			trimmedExt = "." + trimmedExt
+	// log.Fatalf skips deferred calls, so close the store once it is open
+	// and remove the clone before exiting
+	var store *storage.BadgerStorage
+	fatalf := func(format string, args ...any) {
+		if store != nil {
+			store.Close()
 		}
-		extMap[trimmedExt] = true
+		cleanup()
+		log.Fatalf(format, args...)
 	}
 
-	// Map to hold documentation for each file
-	docMap := make(map[string]string)
+	// Record the documented commit on every page; local paths need not be git work trees
+	commit, err := collector.HeadCommit(ctx, repoPath)
+	if err != nil && *repoURL != "" {
+		fatalf("Failed to resolve cloned commit: %v", err)
+	}
+	if commit != "" {
+		fmt.Fprintf(console, "Documenting commit %s\n", commit)
+	}
 
-	// Initialize reference map
-	references := make(map[string][]string)
+	// Initialize Storage using NewBadgerStorage
+	if *storageDir == "" {
+		*storageDir = filepath.Join(outputDir, "storage")
+	}
+	fsCollector.SetExclude(*storageDir)
+	store, err = storage.NewBadgerStorage(*storageDir)
+	if err != nil {
+		fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+
+	// Cache LLM responses in the store so unchanged files are not re-sent
+	if *clearCache {
+		if err := store.ClearResponseCache(); err != nil {
+			fatalf("Failed to clear response cache: %v", err)
+		}
+		fmt.Fprintln(console, "Response cache cleared.")
+	}
+	if llmAnalyzer != nil {
+		llmAnalyzer.SetCache(analyzer.NewResponseCache(store, *noCache))
+	}
 
-	// Walk through the project files
-	err = filepath.Walk(repoPath, func(pathStr string, info os.FileInfo, err error) error {
+	// Record per-file progress so an interrupted run can be resumed
+	var checkpoint *generator.Checkpoint
+	if retryFailed && *resume == "" {
+		*resume, err = latestFailedRun(store)
 		if err != nil {
-			log.Printf("Error accessing path %s: %v", pathStr, err)
-			return err
-		}
-		if !info.IsDir() && extMap[filepath.Ext(pathStr)] {
-			fmt.Println("Analyzing file:", pathStr)
-			code, err := os.ReadFile(pathStr)
-			if err != nil {
-				log.Printf("Failed to read file %s: %v", pathStr, err)
-				return err
-			}
+			fatalf("Failed to find a run to retry: %v", err)
+		}
+	}
+	if *resume != "" {
+		checkpoint, err = generator.ResumeRun(store, *resume, repoPath)
+		if err != nil {
+			fatalf("Failed to resume run: %v", err)
+		}
+		fmt.Fprintf(console, "Resuming run %s\n", checkpoint.ID())
+	} else {
+		source := *path
+		if *repoURL != "" {
+			source = *repoURL
+		}
+		checkpoint, err = generator.StartRun(store, source, repoPath)
+		if err != nil {
+			fatalf("Failed to start run: %v", err)
+		}
+		fmt.Fprintf(console, "Run ID: %s\n", checkpoint.ID())
+	}
+	if retryFailed {
+		failures := checkpoint.Failures()
+		if len(failures) == 0 {
+			fmt.Fprintf(console, "Run %s has no failed files.\n", checkpoint.ID())
+			return
+		}
+		fmt.Fprintf(console, "Retrying %d failed files\n", len(failures))
+	}
 
-			// TODO: Extract imports using a simpler method since analysis package is removed
-			references[pathStr] = []string{} // Empty for now
-
-			// Generate documentation using OpenAI
-			prompt := fmt.Sprintf("Please analyze this %s code and provide comprehensive documentation:\n\n%s",
-				strings.TrimPrefix(filepath.Ext(pathStr), "."),
-				string(code))
-
-			resp, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-				Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-					openai.UserMessage(prompt),
-				}),
-				Model: openai.F(openai.ChatModelChatgpt4oLatest),
-			})
-			if err != nil {
-				log.Printf("Failed to generate documentation for %s: %v", pathStr, err)
-				return err
-			}
+	// Collect, analyze, store and render the project
+	fileCollector := pipeline.NewCollector(fsCollector, strings.Split(*extensions, ","))
+	fileCollector.SetFilter(filter)
+	p := pipeline.New(
+		fileCollector,
+		pipeline.NewStructuredAnalyzer(fileAnalyzer),
+		pipeline.NewReferenceResolver(store),
+		pipeline.NewPersister(store),
+		pipeline.NewDocsRenderer(outputDir),
+	)
+	p.SetCheckpoint(checkpoint)
+	p.SetProgress(tracker)
+	p.SetLedger(ledger)
+	p.SetOutdatedOnly(*outdatedOnly)
+	p.SetRenderPartial(*renderPartial)
+	p.SetContinueOnError(*continueOnError || retryFailed)
+	p.SetCommit(commit)
+	if concurrency != nil {
+		p.SetWorkers(config.LLM.MaxConcurrency)
+	}
+	if retryFailed {
+		p.SetSelect(checkpoint.IsFailed)
+	}
 
-			if len(resp.Choices) > 0 {
-				docMap[pathStr] = resp.Choices[0].Message.Content
-			}
-			fmt.Println("Documentation generated for:", pathStr)
+	status, err := p.Run(ctx, repoPath)
+	switch {
+	case status == storage.RunInterrupted:
+		fmt.Fprintln(console, "Interrupted; the files documented so far were saved.")
+	case err != nil:
+		log.Printf("Run failed: %v", err)
+	default:
+		fmt.Fprintln(console, "Markdown documentation generated successfully.")
+	}
+
+	// Generate the wiki from the stored pages once the run has completed
+	var wikiErr error
+	if *wikiDir != "" && status == storage.RunCompleted {
+		wiki := handlers.NewGenerator(store)
+		wiki.SetProgress(tracker)
+		wikiErr = wiki.Generate(handlers.Config{OutputDir: *wikiDir, ProjectName: projectName(*path, *repoURL)})
+		if wikiErr != nil {
+			log.Printf("Failed to generate wiki: %v", wikiErr)
+		} else {
+			fmt.Fprintf(console, "Wiki generated in %s\n", *wikiDir)
 		}
-		return nil
-	})
+	}
 
-	if err != nil {
-		log.Fatalf("Error processing files: %v", err)
+	counts := checkpoint.Counts()
+	fmt.Fprintf(console, "Run %s %s: %d done, %d pending, %d failed\n", checkpoint.ID(), status,
+		counts[storage.FileDone], counts[storage.FilePending], counts[storage.FileFailed])
+	pipeline.WriteSkipReport(console, fileCollector.Skipped())
+	failures := checkpoint.Failures()
+	if len(failures) > 0 {
+		pipeline.WriteFailureReport(console, failures)
+		fmt.Fprintf(console, "Retry them with: %s\n", rerunCommand("retry-failed", checkpoint.ID()))
+	} else if status != storage.RunCompleted {
+		fmt.Fprintf(console, "Resume with: %s\n", rerunCommand("", checkpoint.ID()))
 	}
 
-	// Generate Markdown documentation
-	err = docs.GenerateDocumentation(repoPath, docMap, references)
-	if err != nil {
-		log.Fatalf("Failed to generate Markdown documentation: %v", err)
+	if provider != nil {
+		ledger.WriteSummary(console, 10)
+		concurrency.WriteSummary(console)
 	}
 
-	fmt.Println("Markdown documentation generated successfully.")
+	switch {
+	case status == storage.RunInterrupted:
+		store.Close()
+		cleanup()
+		os.Exit(130)
+	case status == storage.RunFailed || len(failures) > 0 || wikiErr != nil:
+		store.Close()
+		cleanup()
+		os.Exit(1)
+	}
 
-	// Initialize Storage using NewBadgerStorage
-	store, err := storage.NewBadgerStorage(filepath.Join(repoPath, "storage"))
-	if err != nil {
-		log.Fatalf("Failed to initialize storage: %v", err)
+	fmt.Fprintln(console, "Documentation process completed successfully.")
+}
+
+// projectName names the project after the last element of its path or URL
+func projectName(path, repoURL string) string {
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return filepath.Base(path)
 	}
-	defer store.Close()
+	return strings.TrimSuffix(filepath.Base(strings.TrimRight(repoURL, "/")), ".git")
+}
 
-	// Save documents and references to storage
-	for path, doc := range docMap {
-		document := &storage.Document{
-			ID:         generateID(path),
-			Path:       path,
-			Type:       storage.TypeModule,
-			Content:    doc,
-			References: references[path],
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
-		}
-		if err := store.SaveDocument(document); err != nil {
-			log.Printf("Failed to save document %s: %v", path, err)
-		}
-		// Save references
-		for _, ref := range references[path] {
-			reference := &storage.Reference{
-				SourceID:  document.ID,
-				TargetID:  generateID(ref),
-				Type:      "import",
-				CreatedAt: time.Now(),
-			}
-			if err := store.SaveReference(reference); err != nil {
-				log.Printf("Failed to save reference from %s to %s: %v", path, ref, err)
+// latestFailedRun returns the ID of the most recent run with failed files
+func latestFailedRun(store storage.RunStore) (string, error) {
+	runs, err := store.ListRuns()
+	if err != nil {
+		return "", fmt.Errorf("failed to list runs: %w", err)
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		states, err := store.ListFileStates(runs[i].ID)
+		if err != nil {
+			return "", fmt.Errorf("failed to load run files: %w", err)
+		}
+		for _, state := range states {
+			if state.Status == storage.FileFailed {
+				return runs[i].ID, nil
 			}
 		}
 	}
+	return "", fmt.Errorf("no run has failed files")
+}
 
-	fmt.Println("Documentation process completed successfully.")
+// rerunCommand returns the command line that runs subcommand on run id with
+// the flags given to this run, so the same source, storage and output are used
+func rerunCommand(subcommand, id string) string {
+	parts := []string{"autodoc"}
+	if subcommand != "" {
+		parts = append(parts, subcommand)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "resume" {
+			parts = append(parts, "-"+f.Name+"="+shellQuote(f.Value.String()))
+		}
+	})
+	return strings.Join(append(parts, "-resume", shellQuote(id)), " ")
+}
+
+// shellQuote quotes s for a POSIX shell unless it is made only of safe characters
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:,=@+", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
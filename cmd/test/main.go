@@ -4,20 +4,22 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
-	"time"
+	"syscall"
 
 	analyzer "github.com/rgehrsitz/AutoDoc/internal/analysis"
 	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/generator"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/internal/pipeline"
+	"github.com/rgehrsitz/AutoDoc/internal/prompts"
 	"github.com/rgehrsitz/AutoDoc/internal/storage"
 	"github.com/rgehrsitz/AutoDoc/pkg/config"
-	"github.com/rgehrsitz/AutoDoc/internal/docs"
 )
 
 // getProjectRoot returns the absolute path to the project root directory
@@ -39,6 +41,10 @@ func getProjectRoot() (string, error) {
 }
 
 func main() {
+	// Stop analyzing on SIGINT or SIGTERM so the database is closed cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Get project root path
 	projectRoot, err := getProjectRoot()
 	if err != nil {
@@ -86,155 +92,95 @@ func main() {
 
 	// 3. Initialize collector
 	collector := collector.NewCollector()
+	collector.SetExclude(dbDir)
 
 	// 4. Initialize analyzer
-	openAIAnalyzer := analyzer.NewAnalyzer(cfg.OpenAIKey)
+	var fileAnalyzer analyzer.FileAnalyzer
+	var ledger *llm.Ledger
+	if cfg.LLM.Offline() {
+		fileAnalyzer = analyzer.NewStaticAnalyzer()
+	} else {
+		provider, err := llm.NewProvider(cfg.LLM)
+		if err != nil {
+			log.Fatalf("Failed to initialize LLM provider: %v", err)
+		}
+		ledger = llm.NewLedger(llm.NewPriceTable(cfg.LLM.Prices), 0, 0)
+		library, err := prompts.Load(cfg.LLM.PromptsDir)
+		if err != nil {
+			log.Fatalf("Failed to load prompt templates: %v", err)
+		}
+		throttled := generator.NewThrottledProvider(provider,
+			generator.NewRateLimiterFromConfig(cfg.LLM), generator.DefaultRetryConfig())
+		llmAnalyzer := analyzer.NewAnalyzer(llm.NewMeteredProvider(throttled, ledger))
+		llmAnalyzer.SetPrompts(library)
+		llmAnalyzer.SetCache(analyzer.NewResponseCache(store, false))
+		llmAnalyzer.SetRepairAttempts(cfg.LLM.RepairAttempts)
+		if cfg.LLM.ContextTokens > 0 {
+			llmAnalyzer.SetContextWindow(cfg.LLM.ContextTokens)
+		}
+		fileAnalyzer = llmAnalyzer
+	}
 
 	// 5. Create sample files for testing
 	if err := createSampleFiles(sampleDir); err != nil {
 		log.Fatalf("Failed to create sample files: %v", err)
 	}
 
-	// 6. Collect files
-	files, err := collector.CollectFiles(context.Background(), sampleDir)
-	if err != nil {
-		log.Fatalf("Failed to collect files: %v", err)
+	// 6. Collect, analyze, store and render the sample files, printing each analysis
+	docsOutDir := filepath.Join(testDataDir, "docs_out")
+	if err := os.MkdirAll(docsOutDir, 0755); err != nil {
+		log.Fatalf("Failed to create docs output directory: %v", err)
 	}
 
-	log.Printf("Found %d files to analyze", len(files))
-
-	// 7. Analyze each file and store results
-	for _, file := range files {
-		log.Printf("Analyzing file: %s", file.Path)
-
-		// Perform analysis
-		analysis, rawResponse, err := openAIAnalyzer.AnalyzeFile(context.Background(), file)
-		if err != nil {
-			log.Printf("Error analyzing file %s: %v", file.Path, err)
-			log.Printf("Raw response: %s", rawResponse)
-			continue
-		}
-
-		// Debug: Print raw response
-		log.Printf("Raw OpenAI response for %s:\n%s\n", file.Path, rawResponse)
-
-		// Create document from analysis
-		doc := &storage.Document{
-			ID:         generateID(file.Path),
-			Path:       file.Path,
-			Type:       storage.TypeModule,
-			Content:    analysis.Purpose,
-			Purpose:    analysis.Purpose,
-			Components: make([]storage.ComponentInfo, len(analysis.Components)),
-			Relations:  make([]storage.RelationInfo, len(analysis.Relations)),
-			Insights:   analysis.Insights,
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
-		}
-
-		// Convert components
-		for i, comp := range analysis.Components {
-			doc.Components[i] = storage.ComponentInfo{
-				Name:            comp.Name,
-				Type:            comp.Type,
-				Description:     comp.Description,
-				Visibility:      comp.Visibility,
-				Dependencies:    comp.Dependencies,
-				NotableFeatures: comp.NotableFeatures,
-			}
-		}
-
-		// Convert relations
-		for i, rel := range analysis.Relations {
-			doc.Relations[i] = storage.RelationInfo{
-				From: rel.From,
-				To:   rel.To,
-				Type: rel.Type,
-			}
-		}
-
-		// Store document
-		if err := store.SaveDocument(doc); err != nil {
-			log.Printf("Error storing document %s: %v", file.Path, err)
-			continue
-		}
-
-		// Initialize reference processor
-		refProcessor := analyzer.NewReferenceProcessor(store)
-
-		// Process and store references
-		if err := refProcessor.ProcessReferences(doc, analysis); err != nil {
-			log.Printf("Error processing references for %s: %v", file.Path, err)
-			continue
-		}
-
-		log.Printf("Processed references for: %s", file.Path)
-
-		// Print analysis results
-		printAnalysis(file.Path, analysis)
+	p := pipeline.New(
+		pipeline.NewCollector(collector, nil),
+		&printingAnalyzer{Analyzer: pipeline.NewStructuredAnalyzer(fileAnalyzer)},
+		pipeline.NewReferenceResolver(store),
+		pipeline.NewPersister(store),
+		pipeline.NewDocsRenderer(docsOutDir),
+	)
+	p.SetLedger(ledger)
+	// Store each page before the next file is resolved, so interface
+	// implementations in earlier files can be found
+	p.SetBatchSize(1)
+
+	status, err := p.Run(ctx, sampleDir)
+	if err != nil {
+		log.Fatalf("Failed to document sample files (run %s): %v", status, err)
 	}
+	log.Printf("Documentation generated in: %s", docsOutDir)
 
-	// 8. Test cross-file reference retrieval
+	// 7. Test cross-file reference retrieval
 	log.Println("\nTesting cross-file references:")
-	
-	// Create a map to store analyses for documentation generation
-	analysesMap := make(map[string]string)
-	referencesMap := make(map[string][]string)
-
-	for _, file := range files {
-		// Get document and convert analysis to string for documentation
-		doc, err := store.GetDocument(generateID(file.Path))
-		if err != nil {
-			log.Printf("Error getting document for %s: %v", file.Path, err)
-			continue
-		}
-		analysesMap[file.Path] = doc.Content
 
-		// Get references
-		refs, err := store.GetReferences(generateID(file.Path))
+	docs, err := store.ListDocuments(storage.TypeModule)
+	if err != nil {
+		log.Fatalf("Failed to list documents: %v", err)
+	}
+	for _, doc := range docs {
+		refs, err := store.GetReferences(doc.ID)
 		if err != nil {
-			log.Printf("Error getting references for %s: %v", file.Path, err)
+			log.Printf("Error getting references for %s: %v", doc.Path, err)
 			continue
 		}
-		
-		// Convert references to string slice
-		var refStrings []string
-		for _, ref := range refs {
-			refStrings = append(refStrings, ref.TargetID)
-		}
-		referencesMap[file.Path] = refStrings
 
-		backRefs, err := store.GetBackReferences(generateID(file.Path))
+		backRefs, err := store.GetBackReferences(doc.ID)
 		if err != nil {
-			log.Printf("Error getting back references for %s: %v", file.Path, err)
+			log.Printf("Error getting back references for %s: %v", doc.Path, err)
 			continue
 		}
 
-		log.Printf("\nFile: %s", file.Path)
+		log.Printf("\nFile: %s", doc.Path)
 		log.Printf("Dependencies (%d):", len(refs))
 		for _, ref := range refs {
-			refDoc, err := store.GetDocument(ref.TargetID)
-			if err != nil {
-				log.Printf("  - [Error getting target document: %v]", err)
-				continue
-			}
-			log.Printf("  - %s (%s) -> %s", ref.Type, refDoc.Path, ref.TargetID)
+			log.Printf("  - %s -> %s", ref.Type, ref.TargetID)
 		}
 		log.Printf("Used by (%d):", len(backRefs))
 	}
 
-	// 9. Generate HTML documentation
-	log.Println("\nGenerating HTML documentation...")
-	docsOutDir := filepath.Join(testDataDir, "docs_out")
-	if err := os.MkdirAll(docsOutDir, 0755); err != nil {
-		log.Fatalf("Failed to create docs output directory: %v", err)
+	if ledger != nil {
+		ledger.WriteSummary(os.Stdout, 10)
 	}
-
-	if err := docs.GenerateDocumentation(docsOutDir, analysesMap, referencesMap); err != nil {
-		log.Fatalf("Failed to generate documentation: %v", err)
-	}
-
-	log.Printf("Documentation generated in: %s", docsOutDir)
 }
 
 func createSampleFiles(sampleDir string) error {
@@ -303,17 +249,18 @@ func printAnalysis(path string, analysis *analyzer.Analysis) {
 	}
 }
 
-func generateID(path string) string {
-	// Get package name from path
-	pkgName := filepath.Base(filepath.Dir(path))
-	if pkgName == "." {
-		pkgName = "main"
-	}
-
-	// Create stable ID string
-	idStr := pkgName + ":" + filepath.Base(path)
+// printingAnalyzer prints every analysis as the pipeline produces it
+type printingAnalyzer struct {
+	pipeline.Analyzer
+}
 
-	// Hash the string for a stable ID
-	hash := sha256.Sum256([]byte(idStr))
-	return hex.EncodeToString(hash[:8]) // Use first 8 bytes for readable length
+// Analyze analyzes file and prints the raw response and the analysis
+func (a *printingAnalyzer) Analyze(ctx context.Context, file collector.FileInfo) (*pipeline.Result, error) {
+	result, err := a.Analyzer.Analyze(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Raw response for %s:\n%s\n", file.Path, result.Raw)
+	printAnalysis(file.Path, result.Analysis)
+	return result, nil
 }
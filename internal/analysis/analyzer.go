@@ -6,10 +6,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
 	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/internal/prompts"
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
 )
 
 // Analysis represents the LLM's understanding of a code file
@@ -28,6 +30,8 @@ type Component struct {
 	Visibility      string   `json:"visibility"`
 	Dependencies    []string `json:"dependencies"`
 	NotableFeatures []string `json:"notable_features"`
+	StartLine       int      `json:"start_line,omitempty"` // First line of the chunk the component was found in
+	EndLine         int      `json:"end_line,omitempty"`   // Last line of the chunk the component was found in
 }
 
 // Relation represents a relationship between components
@@ -39,103 +43,123 @@ type Relation struct {
 
 // Analyzer handles code analysis using LLM
 type Analyzer struct {
-	openAI  *openai.Client
-	prompts map[string]string
+	provider       llm.Provider
+	prompts        *prompts.Library
+	cache          *ResponseCache
+	repairAttempts int
+	contextWindow  int
 }
 
 // NewAnalyzer creates a new Analyzer instance
-func NewAnalyzer(openAIKey string) *Analyzer {
+func NewAnalyzer(provider llm.Provider) *Analyzer {
 	return &Analyzer{
-		openAI: openai.NewClient(
-			option.WithAPIKey(openAIKey),
-		),
-		prompts: loadPrompts(),
+		provider:       provider,
+		prompts:        prompts.Default(),
+		repairAttempts: config.DefaultRepairAttempts,
+		contextWindow:  llm.ContextWindow(provider.Model()),
 	}
 }
 
-// AnalyzeFile analyzes a single file using the LLM
-func (a *Analyzer) AnalyzeFile(ctx context.Context, file collector.FileInfo) (*Analysis, string, error) {
-	// Get appropriate prompt for file type
-	prompt := a.getPrompt(file.Language, file.Type)
-
-	// Create chat completion request
-	resp, err := a.openAI.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(a.prompts["system"]),
-			openai.UserMessage(fmt.Sprintf(prompt, file.Content)),
-		}),
-		Model: openai.F(openai.ChatModelChatgpt4oLatest),
-	})
-	if err != nil {
-		return nil, "", fmt.Errorf("OpenAI API error: %w", err)
-	}
-
-	if len(resp.Choices) == 0 {
-		return nil, "", fmt.Errorf("no response from LLM")
-	}
-
-	rawResponse := resp.Choices[0].Message.Content
-
-	// Parse the response into our Analysis struct
-	var analysis Analysis
-	if err := json.Unmarshal([]byte(rawResponse), &analysis); err != nil {
-		return nil, rawResponse, fmt.Errorf("failed to parse analysis: %w", err)
-	}
+// SetPrompts replaces the built-in prompt templates
+func (a *Analyzer) SetPrompts(library *prompts.Library) {
+	a.prompts = library
+}
 
-	return &analysis, rawResponse, nil
+// SetContextWindow overrides the model context window, in tokens, used to
+// decide when a file must be chunked
+func (a *Analyzer) SetContextWindow(tokens int) {
+	a.contextWindow = tokens
 }
 
-// loadPrompts loads the analysis prompts
-func loadPrompts() map[string]string {
-	return map[string]string{
-		"system": `You are an expert code analyzer. Analyze the provided code and return ONLY a JSON object with the following structure:
-{
-    "purpose": "Brief description of the code's purpose",
-    "components": [
-        {
-            "name": "Component name",
-            "type": "Type of component",
-            "description": "Component description",
-            "visibility": "public/private/etc",
-            "dependencies": ["List of dependencies"],
-            "notable_features": ["List of notable features"]
-        }
-    ],
-    "relationships": [
-        {
-            "from": "Source component",
-            "to": "Target component",
-            "type": "Relationship type"
-        }
-    ],
-    "insights": ["List of important observations"]
+// SetRepairAttempts sets how many times an invalid response is sent back for repair
+func (a *Analyzer) SetRepairAttempts(attempts int) {
+	a.repairAttempts = attempts
 }
-Do not include any text before or after the JSON. Do not use markdown formatting.`,
 
-		"go_source": `Analyze this Go code and provide your analysis as a JSON object matching the specified structure:
+// SetCache enables response caching for this analyzer
+func (a *Analyzer) SetCache(cache *ResponseCache) {
+	a.cache = cache
+}
 
-%s`,
+// PromptVersion identifies the prompt template used for files of the given
+// language and type
+func (a *Analyzer) PromptVersion(language, fileType string) string {
+	return a.prompts.TemplateVersion(prompts.Analysis, language, fileType)
+}
 
-		"csharp_source": `Analyze this C# code and provide your analysis as a JSON object matching the specified structure:
+// AnalyzeFile analyzes a single file using the LLM
+func (a *Analyzer) AnalyzeFile(ctx context.Context, file collector.FileInfo) (*Analysis, string, error) {
+	// Reuse a cached response for unchanged content
+	version := a.PromptVersion(file.Language, file.Type)
+	keyContent := file.Content
+	if deps := FormatDependencies(file.Dependencies); deps != "" {
+		keyContent = deps + "\n" + keyContent // The dependency context changes the prompt
+	}
+	cacheKey := CacheKey(keyContent, version, a.provider.Model())
+	if cached, ok := a.cache.Get(cacheKey); ok {
+		var analysis Analysis
+		if err := json.Unmarshal([]byte(cached), &analysis); err == nil {
+			return &analysis, cached, nil
+		}
+	}
 
-%s`,
+	budget, err := a.contentBudget(ctx, file)
+	if err != nil {
+		return nil, "", err
+	}
 
-		"csharp_project": `Analyze this C# project file and provide your analysis as a JSON object matching the specified structure:
+	// Files that do not fit the context window are analyzed chunk by chunk
+	var analysis *Analysis
+	var rawResponse string
+	if llm.EstimateTokens(file.Content) > budget {
+		analysis, rawResponse, err = a.analyzeChunked(ctx, file, budget)
+	} else {
+		var messages []llm.Message
+		messages, err = a.analysisMessages(ctx, file, file.Content)
+		if err != nil {
+			return nil, "", err
+		}
+		// Request a schema-conforming analysis, repairing invalid responses
+		analysis, rawResponse, err = requestStructured[Analysis](ctx, a.provider, messages, AnalysisResponseFormat(), a.repairAttempts)
+	}
 
-%s`,
+	// The token estimate is only approximate; when the model still rejects a
+	// request as too large, retry with chunks of half the size
+	for err != nil && llm.Classify(err) == llm.ErrorTooLarge {
+		budget = min(budget, llm.EstimateTokens(file.Content)) / 2
+		if budget < minChunkTokens {
+			break
+		}
+		log.Printf("%s is too large for the model, retrying in chunks of at most %d tokens", file.Path, budget)
+		analysis, rawResponse, err = a.analyzeChunked(ctx, file, budget)
+	}
+	if err != nil {
+		return nil, rawResponse, fmt.Errorf("failed to parse analysis: %w", err)
+	}
 
-		"csharp_solution": `Analyze this C# solution file and provide your analysis as a JSON object matching the specified structure:
+	a.cache.Put(cacheKey, version, a.provider.Model(), rawResponse)
 
-%s`,
-	}
+	return analysis, rawResponse, nil
 }
 
-// getPrompt returns the appropriate prompt for the file type
-func (a *Analyzer) getPrompt(language, fileType string) string {
-	key := fmt.Sprintf("%s_%s", language, fileType)
-	if prompt, ok := a.prompts[key]; ok {
-		return prompt
+// analysisMessages renders the system and analysis prompts for content from
+// file, with the purposes of its dependencies as context
+func (a *Analyzer) analysisMessages(ctx context.Context, file collector.FileInfo, content string) ([]llm.Message, error) {
+	data := prompts.Data{
+		Path:    file.Path,
+		Content: content,
+		Context: FormatDependencies(file.Dependencies),
+	}
+	system, err := a.prompts.Render(prompts.System, file.Language, file.Type, data)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.prompts.Render(prompts.Analysis, file.Language, file.Type, data)
+	if err != nil {
+		return nil, err
 	}
-	// Default to source prompt if specific one not found
-	return a.prompts[fmt.Sprintf("%s_source", language)]
+	return []llm.Message{
+		llm.SystemMessage(system),
+		llm.UserMessage(user),
+	}, nil
 }
@@ -0,0 +1,110 @@
+// autodoc/internal/analysis/analyzer_test.go
+
+package analyzer
+
+import (
+	"context"
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/internal/storage"
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+// record re-records the cassettes against the configured provider:
+//
+//	go test ./internal/analysis -run Replay -args -record
+var record = flag.Bool("record", false, "record LLM cassettes instead of replaying them")
+
+const calculatorSource = `package math
+
+// Calculator performs basic arithmetic.
+type Calculator struct{}
+
+// Add returns the sum of a and b.
+func (c *Calculator) Add(a, b int) int {
+	return a + b
+}
+`
+
+// cassetteProvider returns a provider that replays (or records) testdata/cassettes
+func cassetteProvider(t *testing.T) llm.Provider {
+	t.Helper()
+
+	if *record {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		cfg.LLM.CassetteMode = config.CassetteRecord
+		cfg.LLM.CassetteDir = filepath.Join("testdata", "cassettes")
+		provider, err := llm.NewProvider(cfg.LLM)
+		if err != nil {
+			t.Fatalf("Failed to create recording provider: %v", err)
+		}
+		return provider
+	}
+
+	provider, err := llm.NewProvider(config.LLMConfig{
+		Model:        config.DefaultLLMModel,
+		CassetteMode: config.CassetteReplay,
+		CassetteDir:  filepath.Join("testdata", "cassettes"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create replay provider: %v", err)
+	}
+	return provider
+}
+
+func TestAnalyzeFileReplay(t *testing.T) {
+	a := NewAnalyzer(cassetteProvider(t))
+
+	analysis, raw, err := a.AnalyzeFile(context.Background(), collector.FileInfo{
+		Path:     "math/calculator.go",
+		Language: "go",
+		Type:     "source",
+		Content:  calculatorSource,
+	})
+	if err != nil {
+		t.Fatalf("Failed to analyze file: %v (raw response: %s)", err, raw)
+	}
+
+	if analysis.Purpose == "" {
+		t.Error("Expected a purpose")
+	}
+	if len(analysis.Components) != 2 {
+		t.Errorf("Expected 2 components, got %d", len(analysis.Components))
+	}
+
+	// Feed the analysis through the reference processor
+	store, err := storage.NewBadgerStorage(filepath.Join(t.TempDir(), "db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	doc := &storage.Document{
+		ID:   "calculator",
+		Path: "math/calculator.go",
+		Type: storage.TypeModule,
+	}
+	if err := NewReferenceProcessor(store).ProcessReferences(doc, analysis); err != nil {
+		t.Fatalf("Failed to process references: %v", err)
+	}
+
+	refs, err := store.GetReferences(doc.ID)
+	if err != nil {
+		t.Fatalf("Failed to get references: %v", err)
+	}
+
+	// The source declares a single method on Calculator and nothing else to refer to
+	if len(refs) != 1 {
+		t.Fatalf("Expected 1 reference, got %d: %v", len(refs), refs)
+	}
+	if refs[0].SourceID != doc.ID || refs[0].TargetID != "Calculator" || refs[0].Type != "belongs-to" {
+		t.Errorf("Expected %s belongs-to Calculator, got %s %s %s", doc.ID, refs[0].SourceID, refs[0].Type, refs[0].TargetID)
+	}
+}
@@ -0,0 +1,71 @@
+// autodoc/internal/analysis/cache.go
+
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/storage"
+)
+
+// ResponseCache reuses LLM responses across runs. Entries are keyed by the
+// hash of the analyzed content, the prompt template version and the model,
+// so changing any of the three produces a fresh request.
+type ResponseCache struct {
+	store  storage.ResponseCache
+	bypass bool
+}
+
+// NewResponseCache creates a cache on top of store. When bypass is set,
+// cached entries are ignored but fresh responses are still written back.
+func NewResponseCache(store storage.ResponseCache, bypass bool) *ResponseCache {
+	return &ResponseCache{
+		store:  store,
+		bypass: bypass,
+	}
+}
+
+// CacheKey builds the cache key for a piece of content
+func CacheKey(content, promptVersion, model string) string {
+	contentHash := sha256.Sum256([]byte(content))
+	hash := sha256.Sum256([]byte(hex.EncodeToString(contentHash[:]) + ":" + promptVersion + ":" + model))
+	return hex.EncodeToString(hash[:])
+}
+
+// Get returns the cached response for key, if any
+func (c *ResponseCache) Get(key string) (string, bool) {
+	if c == nil || c.bypass {
+		return "", false
+	}
+
+	entry, err := c.store.GetCachedResponse(key)
+	if err != nil {
+		log.Printf("Warning: failed to read response cache: %v", err)
+		return "", false
+	}
+	if entry == nil {
+		return "", false
+	}
+	return entry.Response, true
+}
+
+// Put stores a response in the cache
+func (c *ResponseCache) Put(key, promptVersion, model, response string) {
+	if c == nil {
+		return
+	}
+
+	err := c.store.SaveCachedResponse(&storage.CachedResponse{
+		Key:           key,
+		Model:         model,
+		PromptVersion: promptVersion,
+		Response:      response,
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to write response cache: %v", err)
+	}
+}
@@ -2,46 +2,85 @@
 
 package analyzer
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
 
 type Chunk struct {
 	Content   string
 	StartLine int
 	EndLine   int
+	Oversized bool // A single declaration larger than the budget, kept whole
 }
 
 // Chunker splits code into manageable pieces for analysis
 type Chunker struct {
 	MaxChunkSize int
+	// Measure returns the size of a piece of text in the same unit as
+	// MaxChunkSize. It defaults to the byte length.
+	Measure func(string) int
 }
 
 func NewChunker(maxChunkSize int) *Chunker {
 	return &Chunker{
 		MaxChunkSize: maxChunkSize,
+		Measure:      func(s string) int { return len(s) },
+	}
+}
+
+// NewTokenChunker creates a chunker whose chunks fit within a token budget
+func NewTokenChunker(maxTokens int) *Chunker {
+	return &Chunker{
+		MaxChunkSize: maxTokens,
+		Measure:      llm.EstimateTokens,
 	}
 }
 
+// Split breaks content into chunks on line boundaries. A chunk only exceeds
+// MaxChunkSize when a single line is larger than the limit.
 func (c *Chunker) Split(content string) []Chunk {
-	lines := strings.Split(content, "\n")
+	measure := c.Measure
+	if measure == nil {
+		measure = func(s string) int { return len(s) }
+	}
+
+	// Keep each line's newline, so content ending in one has no empty last line
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
 	chunks := make([]Chunk, 0)
-	currentChunk := ""
+	var currentChunk strings.Builder
+	currentSize := 0
 	startLine := 1
-	lineCount := 0
 
 	for i, line := range lines {
-		currentChunk += line + "\n"
-		lineCount++
+		lineSize := measure(line)
 
-		if len(currentChunk) >= c.MaxChunkSize || i == len(lines)-1 {
+		// Close the current chunk before it would overflow
+		if currentChunk.Len() > 0 && currentSize+lineSize > c.MaxChunkSize {
 			chunks = append(chunks, Chunk{
-				Content:   currentChunk,
+				Content:   currentChunk.String(),
 				StartLine: startLine,
-				EndLine:   startLine + lineCount - 1,
+				EndLine:   i,
 			})
-			currentChunk = ""
-			startLine += lineCount
-			lineCount = 0
+			currentChunk.Reset()
+			currentSize = 0
+			startLine = i + 1
 		}
+
+		currentChunk.WriteString(line)
+		currentSize += lineSize
+	}
+
+	if currentChunk.Len() > 0 {
+		chunks = append(chunks, Chunk{
+			Content:   currentChunk.String(),
+			StartLine: startLine,
+			EndLine:   len(lines),
+		})
 	}
 
 	return chunks
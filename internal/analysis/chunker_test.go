@@ -0,0 +1,274 @@
+// autodoc/internal/analysis/chunker_test.go
+
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
+
+func TestTokenChunkerSplit(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, "line %03d of some moderately long source code\n", i)
+	}
+	content := strings.TrimSuffix(b.String(), "\n")
+
+	chunks := NewTokenChunker(300).Split(content)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected multiple chunks, got %d", len(chunks))
+	}
+
+	nextLine := 1
+	var rebuilt strings.Builder
+	for i, chunk := range chunks {
+		if chunk.StartLine != nextLine {
+			t.Errorf("Chunk %d starts at line %d, expected %d", i, chunk.StartLine, nextLine)
+		}
+		if tokens := llm.EstimateTokens(chunk.Content); tokens > 300 {
+			t.Errorf("Chunk %d has %d tokens, expected at most 300", i, tokens)
+		}
+		nextLine = chunk.EndLine + 1
+		rebuilt.WriteString(chunk.Content)
+	}
+	if nextLine != 201 {
+		t.Errorf("Expected chunks to cover 200 lines, covered %d", nextLine-1)
+	}
+	if strings.TrimSuffix(rebuilt.String(), "\n") != content {
+		t.Error("Chunks do not reassemble into the original content")
+	}
+}
+
+func TestChunkerSplitTrailingNewline(t *testing.T) {
+	// Two 10-byte lines fill a 20-byte chunk exactly
+	content := "line one\nline two\nline six\nline ten\n"
+
+	chunks := NewChunker(20).Split(content)
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks, got %d: %+v", len(chunks), chunks)
+	}
+	if last := chunks[1]; last.Content != "line six\nline ten\n" || last.StartLine != 3 || last.EndLine != 4 {
+		t.Errorf("Expected lines 3-4 in the last chunk, got %d-%d %q", last.StartLine, last.EndLine, last.Content)
+	}
+	if rebuilt := chunks[0].Content + chunks[1].Content; rebuilt != content {
+		t.Errorf("Expected chunks to reassemble into %q, got %q", content, rebuilt)
+	}
+
+	if chunks := NewChunker(20).Split(""); len(chunks) != 0 {
+		t.Errorf("Expected no chunks for empty content, got %+v", chunks)
+	}
+}
+
+func TestMergeAnalyses(t *testing.T) {
+	parts := []*Analysis{
+		{
+			Purpose:    "First part",
+			Components: []Component{{Name: "Server", Type: "struct", Dependencies: []string{"net/http"}}},
+			Relations:  []Relation{{From: "Server", To: "Handler", Type: "uses"}},
+			Insights:   []string{"Uses HTTP"},
+		},
+		{
+			Purpose: "Second part",
+			Components: []Component{
+				{Name: "Server", Type: "struct", Description: "HTTP server", Dependencies: []string{"log"}},
+				{Name: "Start", Type: "method"},
+			},
+			Relations: []Relation{{From: "Server", To: "Handler", Type: "uses"}, {From: "Start", To: "Server", Type: "method-of"}},
+			Insights:  []string{"Uses HTTP", "Logs requests"},
+		},
+	}
+	chunks := []Chunk{{StartLine: 1, EndLine: 40}, {StartLine: 41, EndLine: 90}}
+
+	merged := mergeAnalyses(parts, chunks)
+
+	if len(merged.Components) != 2 {
+		t.Fatalf("Expected 2 components, got %d", len(merged.Components))
+	}
+	server := merged.Components[0]
+	if server.Description != "HTTP server" {
+		t.Errorf("Expected description from the second chunk, got %q", server.Description)
+	}
+	if len(server.Dependencies) != 2 {
+		t.Errorf("Expected unioned dependencies, got %v", server.Dependencies)
+	}
+	if server.StartLine != 1 || server.EndLine != 90 {
+		t.Errorf("Expected Server to span lines 1-90, got %d-%d", server.StartLine, server.EndLine)
+	}
+	if start := merged.Components[1]; start.StartLine != 41 || start.EndLine != 90 {
+		t.Errorf("Expected Start to span lines 41-90, got %d-%d", start.StartLine, start.EndLine)
+	}
+	if len(merged.Relations) != 2 {
+		t.Errorf("Expected 2 relations, got %d", len(merged.Relations))
+	}
+	if len(merged.Insights) != 2 {
+		t.Errorf("Expected 2 insights, got %d", len(merged.Insights))
+	}
+}
+
+func TestAnalyzeFileChunksLargeFiles(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("package funcs\n\n")
+	for i := 0; i < 400; i++ {
+		fmt.Fprintf(&b, "func f%03d() int { return %d }\n", i, i)
+	}
+
+	part := `{"purpose": "Declares functions", "components": [{"name": "f", "type": "function", "description": "", "visibility": "private", "dependencies": [], "notable_features": []}], "relationships": [], "insights": []}`
+	provider := &scriptedProvider{}
+	a := NewAnalyzer(provider)
+	a.SetContextWindow(reservedOutputTokens + 2500)
+
+	// One response per chunk, then the purpose summary
+	budget, err := a.contentBudget(context.Background(), collector.FileInfo{Path: "big.go", Language: "go", Type: "source"})
+	if err != nil {
+		t.Fatalf("Failed to compute content budget: %v", err)
+	}
+	chunks := NewGoChunker(budget).Split(b.String())
+	for range chunks {
+		provider.responses = append(provider.responses, part)
+	}
+	provider.responses = append(provider.responses, "Declares many small functions.")
+
+	analysis, _, err := a.AnalyzeFile(context.Background(), collector.FileInfo{
+		Path: "funcs.go", Language: "go", Type: "source", Content: b.String(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to analyze file: %v", err)
+	}
+
+	if len(chunks) < 2 {
+		t.Fatalf("Expected the file to be split into chunks, got %d", len(chunks))
+	}
+	if len(provider.requests) != len(chunks)+1 {
+		t.Errorf("Expected %d requests, got %d", len(chunks)+1, len(provider.requests))
+	}
+	if analysis.Purpose != "Declares many small functions." {
+		t.Errorf("Expected the summarized purpose, got %q", analysis.Purpose)
+	}
+	if len(analysis.Components) != 1 {
+		t.Errorf("Expected de-duplicated components, got %d", len(analysis.Components))
+	}
+	if comp := analysis.Components[0]; comp.StartLine != 1 || comp.EndLine < 400 {
+		t.Errorf("Expected component to span the whole file, got %d-%d", comp.StartLine, comp.EndLine)
+	}
+}
+
+func TestGoChunkerKeepsDeclarationsWhole(t *testing.T) {
+	const preamble = "//go:build linux\n\n// Package big is large.\npackage big\n\n// The imports are repeated in every chunk.\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n"
+	const header = "package big\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\n"
+
+	var b strings.Builder
+	b.WriteString(preamble + "\n")
+	b.WriteString("// Table is a generated lookup table.\nvar Table = map[string]int{\n")
+	for i := 0; i < 150; i++ {
+		fmt.Fprintf(&b, "\t\"key%03d\": %d,\n", i, i)
+	}
+	b.WriteString("}\n\n")
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&b, "// F%02d formats a value.\nfunc F%02d(v int) string {\n\treturn strings.TrimSpace(fmt.Sprint(v))\n}\n\n", i, i)
+	}
+	content := b.String()
+	lines := strings.SplitAfter(content, "\n")
+	lines = lines[:len(lines)-1]
+
+	chunks := NewGoChunker(400).Split(content)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected multiple chunks, got %d", len(chunks))
+	}
+
+	// The first chunk keeps the whole preamble, build constraint included
+	if !strings.HasPrefix(chunks[0].Content, preamble) {
+		t.Errorf("Expected the first chunk to start with the preamble:\n%s", chunks[0].Content)
+	}
+
+	nextLine := 1
+	for i, chunk := range chunks {
+		if chunk.StartLine != nextLine {
+			t.Errorf("Chunk %d starts at line %d, expected %d", i, chunk.StartLine, nextLine)
+		}
+		nextLine = chunk.EndLine + 1
+
+		// Every chunk holds its lines, after the header for all but the first
+		body := strings.Join(lines[chunk.StartLine-1:chunk.EndLine], "")
+		if i > 0 {
+			body = header + body
+		}
+		if chunk.Content != body {
+			t.Errorf("Chunk %d does not hold lines %d-%d:\n%s", i, chunk.StartLine, chunk.EndLine, chunk.Content)
+		}
+
+		// Only the oversized table exceeds the budget, in a chunk of its own
+		table := strings.Contains(chunk.Content, "var Table")
+		if table != chunk.Oversized {
+			t.Errorf("Expected chunk %d to be oversized only if it holds the table, got %v", i, chunk.Oversized)
+		}
+		if table && strings.Contains(chunk.Content, "func F") {
+			t.Errorf("Expected the oversized table alone in chunk %d", i)
+		}
+		if tokens := llm.EstimateTokens(chunk.Content); !table && tokens > 400 {
+			t.Errorf("Chunk %d has %d tokens, expected at most 400", i, tokens)
+		}
+
+		// Every chunk parses on its own, so no declaration was cut
+		if _, err := parser.ParseFile(token.NewFileSet(), "", chunk.Content, parser.ParseComments); err != nil {
+			t.Errorf("Chunk %d does not parse: %v", i, err)
+		}
+		// A chunk never starts at a func without its doc comment
+		if first := strings.TrimSpace(lines[chunk.StartLine-1]); strings.HasPrefix(first, "func") {
+			t.Errorf("Chunk %d starts at a func without its doc comment", i)
+		}
+	}
+	if nextLine != len(lines)+1 {
+		t.Errorf("Expected chunks to cover the file, covered %d of %d lines", nextLine-1, len(lines))
+	}
+}
+
+// sizeLimitedProvider rejects prompts longer than limit as too large
+type sizeLimitedProvider struct {
+	scriptedProvider
+	limit    int
+	rejected int
+}
+
+func (s *sizeLimitedProvider) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	prompt := req.Messages[len(req.Messages)-1].Content
+	if len(prompt) > s.limit {
+		s.rejected++
+		return nil, &llm.APIError{StatusCode: 400, Code: "context_length_exceeded"}
+	}
+	if strings.HasPrefix(prompt, "The following are summaries") {
+		return &llm.ChatResponse{Content: "Declares functions."}, nil
+	}
+	return &llm.ChatResponse{Content: `{"purpose": "Declares functions", "components": [], "relationships": [], "insights": []}`}, nil
+}
+
+func TestAnalyzeFileChunksOnTooLargeError(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("package funcs\n\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, "func f%03d() int { return %d }\n", i, i)
+	}
+
+	// The content fits the estimated budget, but the model disagrees
+	provider := &sizeLimitedProvider{limit: 4000}
+	a := NewAnalyzer(provider)
+
+	analysis, _, err := a.AnalyzeFile(context.Background(), collector.FileInfo{
+		Path: "funcs.go", Language: "go", Type: "source", Content: b.String(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to analyze file: %v", err)
+	}
+	if provider.rejected == 0 {
+		t.Fatal("Expected the whole-file request to be rejected")
+	}
+	if analysis.Purpose != "Declares functions." {
+		t.Errorf("Expected the merged purpose, got %q", analysis.Purpose)
+	}
+}
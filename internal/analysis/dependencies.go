@@ -0,0 +1,39 @@
+// autodoc/internal/analysis/dependencies.go
+
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+)
+
+// maxDependencies limits how many dependencies are described in a prompt
+const maxDependencies = 20
+
+// maxPurposeLength limits the length of each dependency purpose in a prompt
+const maxPurposeLength = 300
+
+// FormatDependencies renders dependencies as a list for the prompt context
+func FormatDependencies(deps []collector.Dependency) string {
+	var b strings.Builder
+	for i, dep := range deps {
+		if i == maxDependencies {
+			fmt.Fprintf(&b, "- ... and %d more\n", len(deps)-maxDependencies)
+			break
+		}
+		purpose := strings.Join(strings.Fields(dep.Purpose), " ")
+		if len(purpose) > maxPurposeLength {
+			// Cut at the start of a rune so the prompt stays valid UTF-8
+			n := maxPurposeLength
+			for n > 0 && !utf8.RuneStart(purpose[n]) {
+				n--
+			}
+			purpose = purpose[:n] + "..."
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", dep.Path, purpose)
+	}
+	return b.String()
+}
@@ -4,12 +4,12 @@ package analyzer
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
 	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/internal/prompts"
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
 )
 
 // CodeAnalysisSchema defines the structure for our code analysis
@@ -30,42 +30,28 @@ type ArchitecturalInsight struct {
 
 // EnhancedAnalyzer provides advanced code analysis capabilities
 type EnhancedAnalyzer struct {
-	client *openai.Client
+	provider       llm.Provider
+	prompts        *prompts.Library
+	repairAttempts int
 }
 
 // NewEnhancedAnalyzer creates a new instance of EnhancedAnalyzer
-func NewEnhancedAnalyzer(openAIKey string) *EnhancedAnalyzer {
+func NewEnhancedAnalyzer(provider llm.Provider) *EnhancedAnalyzer {
 	return &EnhancedAnalyzer{
-		client: openai.NewClient(
-			option.WithAPIKey(openAIKey),
-		),
+		provider:       provider,
+		prompts:        prompts.Default(),
+		repairAttempts: config.DefaultRepairAttempts,
 	}
 }
 
-// generateSystemPrompt creates a dynamic system prompt for code analysis
-func (ea *EnhancedAnalyzer) generateSystemPrompt(file collector.FileInfo) string {
-	return fmt.Sprintf(`You are an expert software architect analyzing %s code.
-Provide a comprehensive analysis of the code, focusing on architectural insights, code quality, and system interactions.
-Respond with a structured JSON output that captures the nuanced understanding of an experienced architect.
-
-Include the following in your analysis:
-- Architectural patterns and design principles
-- Code complexity and maintainability metrics
-- Potential improvements or refactoring opportunities
-- System and component interactions`, file.Language)
+// SetRepairAttempts sets how many times an invalid response is sent back for repair
+func (ea *EnhancedAnalyzer) SetRepairAttempts(attempts int) {
+	ea.repairAttempts = attempts
 }
 
-// generateAnalysisPrompt creates a dynamic analysis prompt
-func (ea *EnhancedAnalyzer) generateAnalysisPrompt(file collector.FileInfo) string {
-	return fmt.Sprintf(`Analyze this %s code comprehensively:
-
-%s
-
-Please provide a detailed JSON analysis covering:
-- Architectural patterns discovered
-- Code quality metrics
-- Architectural insights and potential improvements
-- Cross-component references`, file.Language, file.Content)
+// SetPrompts replaces the built-in prompt templates
+func (ea *EnhancedAnalyzer) SetPrompts(library *prompts.Library) {
+	ea.prompts = library
 }
 
 // AnalyzeWithInsights performs enhanced analysis of source code
@@ -75,31 +61,24 @@ func (ea *EnhancedAnalyzer) AnalyzeWithInsights(ctx context.Context, file collec
 		return nil, fmt.Errorf("empty file content")
 	}
 
-	// Prepare OpenAI request
-	resp, err := ea.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(ea.generateSystemPrompt(file)),
-			openai.UserMessage(ea.generateAnalysisPrompt(file)),
-		}),
-		Model:       openai.F(openai.ChatModelGPT4o),
-		Temperature: openai.F(0.3), // Lower temperature for more consistent results
-	}, option.WithHeader("Accept", "application/json"))
-
+	data := prompts.Data{Path: file.Path, Content: file.Content}
+	system, err := ea.prompts.Render(prompts.EnhancedSystem, file.Language, file.Type, data)
 	if err != nil {
-		return nil, fmt.Errorf("LLM analysis failed: %w", err)
+		return nil, err
 	}
-
-	// Validate response
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no analysis response received")
+	user, err := ea.prompts.Render(prompts.EnhancedAnalysis, file.Language, file.Type, data)
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse the response
-	var analysis CodeAnalysisSchema
-	content := resp.Choices[0].Message.Content
-	if err := json.Unmarshal([]byte(content), &analysis); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	// Request a schema-conforming analysis, repairing invalid responses
+	analysis, _, err := requestStructured[CodeAnalysisSchema](ctx, ea.provider, []llm.Message{
+		llm.SystemMessage(system),
+		llm.UserMessage(user),
+	}, CodeAnalysisResponseFormat(), ea.repairAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("LLM analysis failed: %w", err)
 	}
 
-	return &analysis, nil
+	return analysis, nil
 }
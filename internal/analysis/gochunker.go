@@ -0,0 +1,174 @@
+// autodoc/internal/analysis/gochunker.go
+
+package analyzer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
+
+// Splitter breaks file content into chunks for analysis
+type Splitter interface {
+	Split(content string) []Chunk
+}
+
+// GoChunker splits Go source on top-level declaration boundaries. It keeps
+// doc comments with the declaration they document and never cuts a
+// declaration: one that alone exceeds the budget gets a chunk of its own,
+// marked Oversized. The first chunk starts with the
+// file's own preamble, such as build constraints and the package doc; every
+// later chunk repeats the package clause and imports so it can be understood
+// on its own.
+type GoChunker struct {
+	MaxChunkSize int
+	Measure      func(string) int
+}
+
+// NewGoChunker creates a Go chunker whose chunks fit within a token budget
+func NewGoChunker(maxTokens int) *GoChunker {
+	return &GoChunker{
+		MaxChunkSize: maxTokens,
+		Measure:      llm.EstimateTokens,
+	}
+}
+
+// goUnit is a contiguous run of source lines ending with one declaration
+type goUnit struct {
+	startLine int
+	endLine   int
+}
+
+// Split breaks Go source into chunks. Content that does not parse falls back
+// to line-based chunking.
+func (c *GoChunker) Split(content string) []Chunk {
+	fallback := &Chunker{MaxChunkSize: c.MaxChunkSize, Measure: c.Measure}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return fallback.Split(content)
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	text := func(start, end int) string {
+		return strings.Join(lines[start-1:end], "")
+	}
+	lineOf := func(pos token.Pos) int { return fset.Position(pos).Line }
+
+	// The preamble runs through the package clause and the last import block;
+	// the header repeated in later chunks keeps only the clause and imports
+	preambleEnd := lineOf(f.Name.End())
+	var header strings.Builder
+	header.WriteString("package " + f.Name.Name + "\n\n")
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		start, end := lineOf(gen.Pos()), lineOf(gen.End())
+		header.WriteString(text(start, end))
+		if end > preambleEnd {
+			preambleEnd = end
+		}
+	}
+	header.WriteString("\n")
+	headerText := header.String()
+
+	// Each unit starts right after the previous one so that doc comments and
+	// floating comments stay with the declaration that follows them. The
+	// first unit also takes the preamble.
+	var units []goUnit
+	next := 1
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+		end := lineOf(decl.End())
+		if end < next || end <= preambleEnd {
+			continue
+		}
+		units = append(units, goUnit{startLine: next, endLine: end})
+		next = end + 1
+	}
+	if len(units) == 0 {
+		return fallback.Split(content)
+	}
+	// Trailing comments belong to the last declaration
+	units[len(units)-1].endLine = len(lines)
+
+	measure := c.Measure
+	if measure == nil {
+		measure = func(s string) int { return len(s) }
+	}
+
+	// The chunk starting at line 1 holds the preamble instead of the header
+	headerSize := measure(headerText)
+	withHeader := func(start int, body string) string {
+		if start == 1 {
+			return body
+		}
+		return headerText + body
+	}
+	budget := func(start int) int {
+		if start == 1 {
+			return c.MaxChunkSize
+		}
+		return c.MaxChunkSize - headerSize
+	}
+
+	var chunks []Chunk
+	var body strings.Builder
+	bodySize := 0
+	start, end := 0, 0
+
+	flush := func() {
+		if body.Len() == 0 {
+			return
+		}
+		chunks = append(chunks, Chunk{
+			Content:   withHeader(start, body.String()),
+			StartLine: start,
+			EndLine:   end,
+		})
+		body.Reset()
+		bodySize = 0
+	}
+
+	for _, u := range units {
+		unit := text(u.startLine, u.endLine)
+		size := measure(unit)
+		if body.Len() > 0 && bodySize+size > budget(start) {
+			flush()
+		}
+
+		// A declaration too large for any chunk is sent whole rather than
+		// cut in the middle of a statement or literal
+		if size > budget(u.startLine) {
+			flush()
+			chunks = append(chunks, Chunk{
+				Content:   withHeader(u.startLine, unit),
+				StartLine: u.startLine,
+				EndLine:   u.endLine,
+				Oversized: true,
+			})
+			continue
+		}
+
+		if body.Len() == 0 {
+			start = u.startLine
+		}
+		body.WriteString(unit)
+		bodySize += size
+		end = u.endLine
+	}
+	flush()
+
+	return chunks
+}
@@ -0,0 +1,212 @@
+// autodoc/internal/analysis/mapreduce.go
+
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/internal/prompts"
+)
+
+const (
+	// reservedOutputTokens is the part of the context window kept free for the response
+	reservedOutputTokens = 4096
+	// minChunkTokens keeps chunks useful even for very small context windows
+	minChunkTokens = 512
+)
+
+// contentBudget returns how many tokens of file content fit in a single
+// request alongside the prompts and the reserved response space
+func (a *Analyzer) contentBudget(ctx context.Context, file collector.FileInfo) (int, error) {
+	header, err := a.chunkHeader(file, Chunk{}, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	messages, err := a.analysisMessages(ctx, file, header)
+	if err != nil {
+		return 0, err
+	}
+	budget := a.contextWindow - reservedOutputTokens - llm.EstimateRequestTokens(messages)
+	if budget < minChunkTokens {
+		return minChunkTokens, nil
+	}
+	return budget, nil
+}
+
+// chunkHeader renders the header placed before the content of a chunk
+func (a *Analyzer) chunkHeader(file collector.FileInfo, chunk Chunk, part, parts int) (string, error) {
+	header, err := a.prompts.Render(prompts.ChunkHeader, file.Language, file.Type, prompts.Data{
+		Path:      file.Path,
+		Part:      part,
+		Parts:     parts,
+		StartLine: chunk.StartLine,
+		EndLine:   chunk.EndLine,
+	})
+	if err != nil {
+		return "", err
+	}
+	return header + "\n\n", nil
+}
+
+// analyzeChunked analyzes a file too large for one request: each chunk is
+// analyzed on its own (map) and the partial results are merged (reduce)
+func (a *Analyzer) analyzeChunked(ctx context.Context, file collector.FileInfo, budget int) (*Analysis, string, error) {
+	chunks := splitterFor(file, budget).Split(file.Content)
+	log.Printf("Splitting %s into %d chunks of at most %d tokens", file.Path, len(chunks), budget)
+
+	parts := make([]*Analysis, 0, len(chunks))
+	for i, chunk := range chunks {
+		if chunk.Oversized {
+			log.Printf("Warning: %s lines %d-%d hold one declaration larger than %d tokens; sending it whole", file.Path, chunk.StartLine, chunk.EndLine, budget)
+		}
+		header, err := a.chunkHeader(file, chunk, i+1, len(chunks))
+		if err != nil {
+			return nil, "", err
+		}
+		messages, err := a.analysisMessages(ctx, file, header+chunk.Content)
+		if err != nil {
+			return nil, "", err
+		}
+		part, raw, err := requestStructured[Analysis](ctx, a.provider, messages, AnalysisResponseFormat(), a.repairAttempts)
+		if err != nil {
+			return nil, raw, fmt.Errorf("chunk %d (lines %d-%d): %w", i+1, chunk.StartLine, chunk.EndLine, err)
+		}
+		parts = append(parts, part)
+	}
+
+	merged := mergeAnalyses(parts, chunks)
+	merged.Purpose = a.mergePurposes(ctx, file, parts)
+
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal merged analysis: %w", err)
+	}
+
+	return merged, string(raw), nil
+}
+
+// splitterFor returns the chunker best suited to a file's language
+func splitterFor(file collector.FileInfo, budget int) Splitter {
+	if file.Language == "go" && file.Type == "source" {
+		return NewGoChunker(budget)
+	}
+	return NewTokenChunker(budget)
+}
+
+// mergePurposes summarizes the purposes of all chunks into one. If the
+// summary request fails, the partial purposes are joined instead.
+func (a *Analyzer) mergePurposes(ctx context.Context, file collector.FileInfo, parts []*Analysis) string {
+	if len(parts) == 1 {
+		return parts[0].Purpose
+	}
+
+	purposes := make([]string, 0, len(parts))
+	for i, part := range parts {
+		purposes = append(purposes, fmt.Sprintf("Part %d: %s", i+1, part.Purpose))
+	}
+
+	prompt, err := a.prompts.Render(prompts.MergePurposes, file.Language, file.Type, prompts.Data{
+		Path:      file.Path,
+		Summaries: strings.Join(purposes, "\n"),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to render purpose summary prompt for %s: %v", file.Path, err)
+		return parts[0].Purpose
+	}
+
+	resp, err := a.provider.Chat(ctx, llm.ChatRequest{
+		Messages: []llm.Message{
+			llm.UserMessage(prompt),
+		},
+	})
+	if err != nil || strings.TrimSpace(resp.Content) == "" {
+		log.Printf("Warning: failed to summarize chunk purposes for %s: %v", file.Path, err)
+		return parts[0].Purpose
+	}
+	return strings.TrimSpace(resp.Content)
+}
+
+// mergeAnalyses combines per-chunk analyses into one. Components with the
+// same name and type are de-duplicated and their line ranges widened,
+// relations and insights are unioned, and the first purpose is kept.
+func mergeAnalyses(parts []*Analysis, chunks []Chunk) *Analysis {
+	merged := &Analysis{
+		Components: []Component{},
+		Relations:  []Relation{},
+		Insights:   []string{},
+	}
+
+	componentIndex := make(map[string]int)
+	seenRelations := make(map[Relation]bool)
+	seenInsights := make(map[string]bool)
+
+	for i, part := range parts {
+		if merged.Purpose == "" {
+			merged.Purpose = part.Purpose
+		}
+
+		for _, comp := range part.Components {
+			if i < len(chunks) && comp.StartLine == 0 {
+				comp.StartLine = chunks[i].StartLine
+				comp.EndLine = chunks[i].EndLine
+			}
+
+			key := strings.ToLower(comp.Type) + ":" + comp.Name
+			idx, exists := componentIndex[key]
+			if !exists {
+				componentIndex[key] = len(merged.Components)
+				merged.Components = append(merged.Components, comp)
+				continue
+			}
+
+			existing := &merged.Components[idx]
+			if existing.Description == "" {
+				existing.Description = comp.Description
+			}
+			if existing.Visibility == "" {
+				existing.Visibility = comp.Visibility
+			}
+			existing.Dependencies = unionStrings(existing.Dependencies, comp.Dependencies)
+			existing.NotableFeatures = unionStrings(existing.NotableFeatures, comp.NotableFeatures)
+			if comp.StartLine > 0 && (existing.StartLine == 0 || comp.StartLine < existing.StartLine) {
+				existing.StartLine = comp.StartLine
+			}
+			if comp.EndLine > existing.EndLine {
+				existing.EndLine = comp.EndLine
+			}
+		}
+
+		for _, rel := range part.Relations {
+			if !seenRelations[rel] {
+				seenRelations[rel] = true
+				merged.Relations = append(merged.Relations, rel)
+			}
+		}
+
+		for _, insight := range part.Insights {
+			if !seenInsights[insight] {
+				seenInsights[insight] = true
+				merged.Insights = append(merged.Insights, insight)
+			}
+		}
+	}
+
+	return merged
+}
+
+// unionStrings appends the values of b missing from a
+func unionStrings(a, b []string) []string {
+	for _, value := range b {
+		if !slices.Contains(a, value) {
+			a = append(a, value)
+		}
+	}
+	return a
+}
@@ -6,42 +6,64 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/internal/prompts"
 )
 
-// OpenAIClient handles interactions with the OpenAI API.
+// OpenAIClient produces free-form documentation for source code through an LLM provider.
 type OpenAIClient struct {
-	client *openai.Client
+	provider llm.Provider
+	prompts  *prompts.Library
+	cache    *ResponseCache
 }
 
-// NewOpenAIClient initializes and returns a new OpenAI client.
-func NewOpenAIClient(apiKey string) *OpenAIClient {
+// NewOpenAIClient initializes and returns a new client backed by the given provider.
+func NewOpenAIClient(provider llm.Provider) *OpenAIClient {
 	return &OpenAIClient{
-		client: openai.NewClient(
-			option.WithAPIKey(apiKey),
-		),
+		provider: provider,
+		prompts:  prompts.Default(),
 	}
 }
 
+// SetPrompts replaces the built-in prompt templates.
+func (c *OpenAIClient) SetPrompts(library *prompts.Library) {
+	c.prompts = library
+}
+
+// SetCache enables response caching for this client.
+func (c *OpenAIClient) SetCache(cache *ResponseCache) {
+	c.cache = cache
+}
+
+// PromptVersion identifies the prompt template used to document source code
+// of the given language.
+func (c *OpenAIClient) PromptVersion(language, fileType string) string {
+	return c.prompts.TemplateVersion(prompts.Document, language, fileType)
+}
+
 // AnalyzeSource analyzes and documents the provided source code.
 func (c *OpenAIClient) AnalyzeSource(ctx context.Context, code string, language string) (string, error) {
-	prompt := fmt.Sprintf("Please analyze this %s code and provide comprehensive documentation:\n\n%s",
-		language, code)
-
-	resp, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(prompt),
-		}),
-		Model: openai.F(openai.ChatModelChatgpt4oLatest),
+	version := c.PromptVersion(language, "source")
+	cacheKey := CacheKey(code, version, c.provider.Model())
+	if cached, ok := c.cache.Get(cacheKey); ok {
+		return cached, nil
+	}
+
+	prompt, err := c.prompts.Render(prompts.Document, language, "source", prompts.Data{Content: code})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.provider.Chat(ctx, llm.ChatRequest{
+		Messages: []llm.Message{
+			llm.UserMessage(prompt),
+		},
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to analyze code: %w", err)
 	}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no completion choices returned")
-	}
+	c.cache.Put(cacheKey, version, c.provider.Model(), resp.Content)
 
-	return resp.Choices[0].Message.Content, nil
+	return resp.Content, nil
 }
@@ -90,30 +90,44 @@ func NewReferenceProcessor(store storage.Storage) *ReferenceProcessor {
 	}
 }
 
-// ProcessReferences coordinates the processing of different types of references
+// ProcessReferences resolves the references of a document and saves them
 func (r *ReferenceProcessor) ProcessReferences(doc *storage.Document, analysis *Analysis) error {
+	refs, err := r.ResolveReferences(doc, analysis)
+	if err != nil {
+		return err
+	}
+	if err := r.store.BatchSaveReferences(refs); err != nil {
+		return fmt.Errorf("failed to save references: %w", err)
+	}
+	return nil
+}
+
+// ResolveReferences coordinates the processing of different types of
+// references and returns them without saving
+func (r *ReferenceProcessor) ResolveReferences(doc *storage.Document, analysis *Analysis) ([]*storage.Reference, error) {
 	// Track unique references more comprehensively
 	processedRefs := make(map[string]bool)
+	var refs []*storage.Reference
 
 	// Process imports and relationships
-	if err := r.processImports(doc, analysis, processedRefs); err != nil {
-		return fmt.Errorf("error processing imports: %w", err)
+	if err := r.processImports(doc, analysis, processedRefs, &refs); err != nil {
+		return nil, fmt.Errorf("error processing imports: %w", err)
 	}
 
-	if err := r.processRelationships(doc, analysis, processedRefs); err != nil {
-		return fmt.Errorf("error processing relationships: %w", err)
+	if err := r.processRelationships(doc, analysis, processedRefs, &refs); err != nil {
+		return nil, fmt.Errorf("error processing relationships: %w", err)
 	}
 
 	// Optional: Process interface implementations if needed
-	if err := r.processInterfaceImplementations(doc, analysis, processedRefs); err != nil {
-		return fmt.Errorf("error processing interface implementations: %w", err)
+	if err := r.processInterfaceImplementations(doc, analysis, processedRefs, &refs); err != nil {
+		return nil, fmt.Errorf("error processing interface implementations: %w", err)
 	}
 
-	return nil
+	return refs, nil
 }
 
 // processImports handles package-level import references
-func (r *ReferenceProcessor) processImports(doc *storage.Document, analysis *Analysis, processedRefs map[string]bool) error {
+func (r *ReferenceProcessor) processImports(doc *storage.Document, analysis *Analysis, processedRefs map[string]bool, refs *[]*storage.Reference) error {
 	importedPackages := make(map[string]bool)
 
 	for _, comp := range analysis.Components {
@@ -161,7 +175,7 @@ func (r *ReferenceProcessor) processImports(doc *storage.Document, analysis *Ana
 				continue
 			}
 
-			// Create and save the reference
+			// Create and collect the reference
 			ref := &storage.Reference{
 				SourceID:  doc.ID,
 				TargetID:  targetPath,
@@ -169,9 +183,7 @@ func (r *ReferenceProcessor) processImports(doc *storage.Document, analysis *Ana
 				CreatedAt: time.Now(),
 			}
 
-			if err := r.store.SaveReference(ref); err != nil {
-				return err
-			}
+			*refs = append(*refs, ref)
 
 			processedRefs[refKey] = true
 			log.Printf("Added import reference: %s -> %s", doc.Path, targetPath)
@@ -181,7 +193,7 @@ func (r *ReferenceProcessor) processImports(doc *storage.Document, analysis *Ana
 }
 
 // processRelationships handles relationships between components
-func (r *ReferenceProcessor) processRelationships(doc *storage.Document, analysis *Analysis, processedRefs map[string]bool) error {
+func (r *ReferenceProcessor) processRelationships(doc *storage.Document, analysis *Analysis, processedRefs map[string]bool, refs *[]*storage.Reference) error {
 	for _, rel := range analysis.Relations {
 		if rel.From == "" || rel.To == "" {
 			continue
@@ -207,7 +219,7 @@ func (r *ReferenceProcessor) processRelationships(doc *storage.Document, analysi
 			continue
 		}
 
-		// Create and save reference
+		// Create and collect reference
 		ref := &storage.Reference{
 			SourceID:  doc.ID,
 			TargetID:  targetPath,
@@ -215,9 +227,7 @@ func (r *ReferenceProcessor) processRelationships(doc *storage.Document, analysi
 			CreatedAt: time.Now(),
 		}
 
-		if err := r.store.SaveReference(ref); err != nil {
-			return err
-		}
+		*refs = append(*refs, ref)
 
 		processedRefs[refKey] = true
 		log.Printf("Added relationship reference: %s -%s-> %s",
@@ -227,7 +237,7 @@ func (r *ReferenceProcessor) processRelationships(doc *storage.Document, analysi
 }
 
 // processInterfaceImplementations checks for interface implementations
-func (r *ReferenceProcessor) processInterfaceImplementations(doc *storage.Document, analysis *Analysis, processedRefs map[string]bool) error {
+func (r *ReferenceProcessor) processInterfaceImplementations(doc *storage.Document, analysis *Analysis, processedRefs map[string]bool, refs *[]*storage.Reference) error {
 	for _, comp := range analysis.Components {
 		if strings.EqualFold(comp.Type, "struct") {
 			// Find interfaces in the same package
@@ -248,7 +258,7 @@ func (r *ReferenceProcessor) processInterfaceImplementations(doc *storage.Docume
 						continue
 					}
 
-					// Create and save reference
+					// Create and collect reference
 					ref := &storage.Reference{
 						SourceID:  doc.ID,
 						TargetID:  iface.ID,
@@ -256,9 +266,7 @@ func (r *ReferenceProcessor) processInterfaceImplementations(doc *storage.Docume
 						CreatedAt: time.Now(),
 					}
 
-					if err := r.store.SaveReference(ref); err != nil {
-						return err
-					}
+					*refs = append(*refs, ref)
 
 					processedRefs[refKey] = true
 					log.Printf("Added interface implementation: %s implements %s",
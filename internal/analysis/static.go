@@ -0,0 +1,885 @@
+// autodoc/internal/analysis/static.go
+
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+)
+
+// FileAnalyzer produces a structured Analysis for a single file
+type FileAnalyzer interface {
+	AnalyzeFile(ctx context.Context, file collector.FileInfo) (*Analysis, string, error)
+	PromptVersion(language, fileType string) string
+}
+
+// SourceAnalyzer produces free-form documentation for a piece of source code
+type SourceAnalyzer interface {
+	AnalyzeSource(ctx context.Context, code string, language string) (string, error)
+	PromptVersion(language, fileType string) string
+}
+
+// StaticVersion identifies documents produced by the static analyzer
+const StaticVersion = "static"
+
+// StaticAnalyzer fills an Analysis using only static parsing. It never calls
+// an LLM, so it can run in CI and air-gapped environments and serves as a
+// baseline to compare LLM output against.
+type StaticAnalyzer struct{}
+
+// NewStaticAnalyzer creates a new StaticAnalyzer instance
+func NewStaticAnalyzer() *StaticAnalyzer {
+	return &StaticAnalyzer{}
+}
+
+// PromptVersion returns StaticVersion, since no prompts are used
+func (s *StaticAnalyzer) PromptVersion(language, fileType string) string {
+	return StaticVersion
+}
+
+// AnalyzeFile analyzes a single file without calling an LLM. The raw response
+// is the JSON encoding of the analysis, mirroring what an LLM would return.
+func (s *StaticAnalyzer) AnalyzeFile(ctx context.Context, file collector.FileInfo) (*Analysis, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var analysis *Analysis
+	var err error
+	switch {
+	case file.Language == "go" && file.Type == "module":
+		analysis = analyzeGoMod(file)
+	case file.Language == "go":
+		analysis, err = analyzeGoSource(file)
+	case file.Language == "csharp" && file.Type == "project":
+		analysis, err = analyzeCSharpProject(file)
+	case file.Language == "csharp" && file.Type == "solution":
+		analysis = analyzeCSharpSolution(file)
+	case file.Language == "csharp":
+		analysis = analyzeCSharpSource(file)
+	default:
+		analysis = analyzeGeneric(file)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("static analysis of %s failed: %w", file.Path, err)
+	}
+
+	raw, err := json.Marshal(analysis)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal analysis: %w", err)
+	}
+
+	return analysis, string(raw), nil
+}
+
+// AnalyzeSource documents source code as markdown without calling an LLM.
+// The language is a collector.Languages name, or the file extension without
+// the leading dot for languages the registry does not know.
+func (s *StaticAnalyzer) AnalyzeSource(ctx context.Context, code string, language string) (string, error) {
+	lang, fileType, ext := language, "source", "."+language
+	if registered, ok := collector.Languages.Language(language); ok && len(registered.Extensions) > 0 {
+		ext = registered.Extensions[0]
+	} else if byExt, extType := collector.Languages.ClassifyExtension(ext); byExt != "" {
+		lang, fileType = byExt, extType
+	}
+
+	analysis, _, err := s.AnalyzeFile(ctx, collector.FileInfo{
+		Path:     "source" + ext,
+		Language: lang,
+		Type:     fileType,
+		Content:  code,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return FormatAnalysisMarkdown(analysis), nil
+}
+
+// FormatAnalysisMarkdown renders an Analysis as a markdown document
+func FormatAnalysisMarkdown(analysis *Analysis) string {
+	var b strings.Builder
+
+	b.WriteString("## Purpose\n\n")
+	b.WriteString(analysis.Purpose + "\n")
+
+	if len(analysis.Components) > 0 {
+		b.WriteString("\n## Components\n\n")
+		for _, comp := range analysis.Components {
+			fmt.Fprintf(&b, "### %s (%s)\n\n", comp.Name, comp.Type)
+			if comp.Description != "" {
+				b.WriteString(comp.Description + "\n\n")
+			}
+			if comp.Visibility != "" {
+				fmt.Fprintf(&b, "- Visibility: %s\n", comp.Visibility)
+			}
+			if len(comp.Dependencies) > 0 {
+				fmt.Fprintf(&b, "- Dependencies: %s\n", strings.Join(comp.Dependencies, ", "))
+			}
+			for _, feature := range comp.NotableFeatures {
+				fmt.Fprintf(&b, "- %s\n", feature)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(analysis.Relations) > 0 {
+		b.WriteString("## Relationships\n\n")
+		for _, rel := range analysis.Relations {
+			fmt.Fprintf(&b, "- %s -%s-> %s\n", rel.From, rel.Type, rel.To)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(analysis.Insights) > 0 {
+		b.WriteString("## Insights\n\n")
+		for _, insight := range analysis.Insights {
+			fmt.Fprintf(&b, "- %s\n", insight)
+		}
+	}
+
+	return b.String()
+}
+
+// analyzeGoSource analyzes Go source code using go/ast
+func analyzeGoSource(file collector.FileInfo) (*Analysis, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file.Path, file.Content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map import paths to their local names, and the names usable in
+	// selector expressions back to their paths; blank and dot imports have
+	// no such name
+	imports := make(map[string]string)
+	names := make(map[string]string)
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := goImportName(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[path] = name
+		if name != "_" && name != "." {
+			names[name] = path
+		}
+	}
+
+	// Collect top-level function names for call relationships
+	localFuncs := make(map[string]bool)
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			localFuncs[fn.Name.Name] = true
+		}
+	}
+
+	analysis := &Analysis{
+		Components: []Component{},
+		Relations:  []Relation{},
+		Insights:   []string{},
+	}
+
+	var undocumented, exported, todos int
+	var longestFunc string
+	var longestLines int
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			comp := goFuncComponent(d, names)
+			if d.Doc == nil {
+				undocumented++
+			}
+			if ast.IsExported(d.Name.Name) {
+				exported++
+			}
+
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				recv := receiverTypeName(d.Recv.List[0].Type)
+				analysis.Relations = append(analysis.Relations, Relation{From: comp.Name, To: recv, Type: "method-of"})
+			}
+
+			for _, callee := range calledLocalFuncs(d, localFuncs) {
+				analysis.Relations = append(analysis.Relations, Relation{From: comp.Name, To: callee, Type: "calls"})
+			}
+
+			lines := fset.Position(d.End()).Line - fset.Position(d.Pos()).Line + 1
+			if lines > longestLines {
+				longestLines = lines
+				longestFunc = comp.Name
+			}
+
+			analysis.Components = append(analysis.Components, comp)
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					doc := sp.Doc
+					if doc == nil {
+						doc = d.Doc
+					}
+					if doc == nil {
+						undocumented++
+					}
+					if ast.IsExported(sp.Name.Name) {
+						exported++
+					}
+
+					comp, rels := goTypeComponent(sp, doc, names)
+					analysis.Components = append(analysis.Components, comp)
+					analysis.Relations = append(analysis.Relations, rels...)
+
+				case *ast.ValueSpec:
+					kind := "variable"
+					if d.Tok == token.CONST {
+						kind = "constant"
+					}
+					for _, name := range sp.Names {
+						if name.Name == "_" {
+							continue
+						}
+						analysis.Components = append(analysis.Components, Component{
+							Name:         name.Name,
+							Type:         kind,
+							Description:  firstSentence(commentText(sp.Doc, d.Doc)),
+							Visibility:   goVisibility(name.Name),
+							Dependencies: selectorDependencies(sp, names),
+						})
+					}
+				}
+			}
+		}
+	}
+
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			upper := strings.ToUpper(c.Text)
+			if strings.Contains(upper, "TODO") || strings.Contains(upper, "FIXME") {
+				todos++
+			}
+		}
+	}
+
+	for path := range imports {
+		analysis.Relations = append(analysis.Relations, Relation{From: f.Name.Name, To: path, Type: "imports"})
+	}
+	sortRelations(analysis.Relations)
+
+	analysis.Purpose = goPurpose(f, analysis)
+
+	analysis.Insights = append(analysis.Insights,
+		fmt.Sprintf("Declares %d top-level components, %d of them exported", len(analysis.Components), exported))
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		analysis.Insights = append(analysis.Insights, fmt.Sprintf("Imports %s", strings.Join(paths, ", ")))
+	}
+	if undocumented > 0 {
+		analysis.Insights = append(analysis.Insights, fmt.Sprintf("%d declarations lack doc comments", undocumented))
+	}
+	if longestFunc != "" && longestLines > 50 {
+		analysis.Insights = append(analysis.Insights, fmt.Sprintf("%s is %d lines long and may benefit from refactoring", longestFunc, longestLines))
+	}
+	if todos > 0 {
+		analysis.Insights = append(analysis.Insights, fmt.Sprintf("Contains %d TODO/FIXME comments", todos))
+	}
+
+	return analysis, nil
+}
+
+// goFuncComponent builds a component for a function or method declaration
+func goFuncComponent(fn *ast.FuncDecl, names map[string]string) Component {
+	name := fn.Name.Name
+	kind := "function"
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		name = receiverTypeName(fn.Recv.List[0].Type) + "." + name
+		kind = "method"
+	}
+
+	var features []string
+	if fn.Type.TypeParams != nil && len(fn.Type.TypeParams.List) > 0 {
+		features = append(features, "Generic")
+	}
+	if params := fn.Type.Params.List; len(params) > 0 {
+		if _, ok := params[len(params)-1].Type.(*ast.Ellipsis); ok {
+			features = append(features, "Variadic")
+		}
+	}
+	if fn.Type.Results != nil {
+		for _, res := range fn.Type.Results.List {
+			if ident, ok := res.Type.(*ast.Ident); ok && ident.Name == "error" {
+				features = append(features, "Returns an error")
+				break
+			}
+		}
+	}
+	if fn.Body != nil {
+		var spawns, channels bool
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n.(type) {
+			case *ast.GoStmt:
+				spawns = true
+			case *ast.SendStmt, *ast.ChanType, *ast.SelectStmt:
+				channels = true
+			}
+			return true
+		})
+		if spawns {
+			features = append(features, "Starts goroutines")
+		}
+		if channels {
+			features = append(features, "Uses channels")
+		}
+	}
+
+	description := firstSentence(commentText(fn.Doc))
+	if description == "" {
+		description = fmt.Sprintf("%s %s with %d parameters", capitalize(kind), name, fieldCount(fn.Type.Params))
+	}
+
+	return Component{
+		Name:            name,
+		Type:            kind,
+		Description:     description,
+		Visibility:      goVisibility(fn.Name.Name),
+		Dependencies:    selectorDependencies(fn, names),
+		NotableFeatures: features,
+	}
+}
+
+// goTypeComponent builds a component for a type declaration along with its relations
+func goTypeComponent(spec *ast.TypeSpec, doc *ast.CommentGroup, names map[string]string) (Component, []Relation) {
+	comp := Component{
+		Name:         spec.Name.Name,
+		Type:         "type",
+		Description:  firstSentence(commentText(doc)),
+		Visibility:   goVisibility(spec.Name.Name),
+		Dependencies: selectorDependencies(spec.Type, names),
+	}
+	var relations []Relation
+
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		comp.Type = "struct"
+		comp.NotableFeatures = append(comp.NotableFeatures, fmt.Sprintf("%d fields", fieldCount(t.Fields)))
+		for _, field := range t.Fields.List {
+			if len(field.Names) == 0 {
+				relations = append(relations, Relation{From: spec.Name.Name, To: typeName(field.Type), Type: "embeds"})
+			}
+		}
+	case *ast.InterfaceType:
+		comp.Type = "interface"
+		for _, method := range t.Methods.List {
+			for _, name := range method.Names {
+				comp.NotableFeatures = append(comp.NotableFeatures, "Requires method "+name.Name)
+			}
+			if len(method.Names) == 0 {
+				relations = append(relations, Relation{From: spec.Name.Name, To: typeName(method.Type), Type: "embeds"})
+			}
+		}
+	}
+
+	if spec.TypeParams != nil && len(spec.TypeParams.List) > 0 {
+		comp.NotableFeatures = append(comp.NotableFeatures, "Generic")
+	}
+	if comp.Description == "" {
+		comp.Description = fmt.Sprintf("%s %s", capitalize(comp.Type), spec.Name.Name)
+	}
+
+	return comp, relations
+}
+
+// goPurpose derives a file purpose from the package doc comment or its contents
+func goPurpose(f *ast.File, analysis *Analysis) string {
+	if f.Doc != nil {
+		if purpose := firstSentence(f.Doc.Text()); purpose != "" {
+			return purpose
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, comp := range analysis.Components {
+		counts[comp.Type]++
+	}
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[kind], pluralize(kind, counts[kind])))
+	}
+
+	purpose := fmt.Sprintf("Go source file in package %s", f.Name.Name)
+	if len(parts) > 0 {
+		purpose += " declaring " + strings.Join(parts, ", ")
+	}
+	return purpose
+}
+
+// goImportName returns the name a package is assumed to be imported under,
+// the way goimports guesses it: the last path element, skipping a major
+// version suffix such as "/v2", without a "go-" prefix and cut at the first
+// character that cannot appear in an identifier, so "gopkg.in/yaml.v3" is
+// "yaml"
+func goImportName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && isDigits(name[1:]) {
+		name = elems[len(elems)-2]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	}); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// isDigits reports whether s is a non-empty string of decimal digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorDependencies returns the imported package members referenced within
+// a node, given the local names of the imports
+func selectorDependencies(node ast.Node, names map[string]string) []string {
+	seen := make(map[string]bool)
+	var deps []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		if _, imported := names[ident.Name]; !imported {
+			return true
+		}
+		dep := ident.Name + "." + sel.Sel.Name
+		if !seen[dep] {
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+		return true
+	})
+	sort.Strings(deps)
+	return deps
+}
+
+// calledLocalFuncs returns the top-level functions of the same file called from a function
+func calledLocalFuncs(fn *ast.FuncDecl, localFuncs map[string]bool) []string {
+	if fn.Body == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var callees []string
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if ident, ok := call.Fun.(*ast.Ident); ok && localFuncs[ident.Name] && !seen[ident.Name] {
+			seen[ident.Name] = true
+			callees = append(callees, ident.Name)
+		}
+		return true
+	})
+	sort.Strings(callees)
+	return callees
+}
+
+// receiverTypeName returns the base type name of a method receiver
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return typeName(expr)
+	}
+}
+
+// typeName renders a type expression as a short string
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return typeName(t.X)
+	case *ast.SelectorExpr:
+		return typeName(t.X) + "." + t.Sel.Name
+	case *ast.IndexExpr:
+		return typeName(t.X)
+	case *ast.IndexListExpr:
+		return typeName(t.X)
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
+
+// fieldCount counts the named (or anonymous) entries in a field list
+func fieldCount(fields *ast.FieldList) int {
+	if fields == nil {
+		return 0
+	}
+	count := 0
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			count++
+		} else {
+			count += len(field.Names)
+		}
+	}
+	return count
+}
+
+// goVisibility maps Go identifier casing to a visibility label
+func goVisibility(name string) string {
+	if ast.IsExported(name) {
+		return "public"
+	}
+	return "private"
+}
+
+// commentText returns the text of the first non-nil comment group
+func commentText(groups ...*ast.CommentGroup) string {
+	for _, g := range groups {
+		if g != nil {
+			return g.Text()
+		}
+	}
+	return ""
+}
+
+// analyzeGoMod analyzes a go.mod file
+func analyzeGoMod(file collector.FileInfo) *Analysis {
+	analysis := &Analysis{
+		Components: []Component{},
+		Relations:  []Relation{},
+		Insights:   []string{},
+	}
+
+	var modulePath, goVersion string
+	var direct, indirect int
+	inRequire := false
+
+	for _, line := range strings.Split(file.Content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "module "):
+			modulePath = strings.TrimSpace(strings.TrimPrefix(line, "module "))
+		case strings.HasPrefix(line, "go "):
+			goVersion = strings.TrimSpace(strings.TrimPrefix(line, "go "))
+		case line == "require (":
+			inRequire = true
+		case inRequire && line == ")":
+			inRequire = false
+		case inRequire || strings.HasPrefix(line, "require "):
+			fields := strings.Fields(strings.TrimPrefix(line, "require "))
+			if len(fields) < 2 {
+				continue
+			}
+			isIndirect := strings.HasSuffix(line, "// indirect")
+			if isIndirect {
+				indirect++
+			} else {
+				direct++
+			}
+			comp := Component{
+				Name:        fields[0],
+				Type:        "dependency",
+				Description: fmt.Sprintf("Module dependency at version %s", fields[1]),
+				Visibility:  "public",
+			}
+			if isIndirect {
+				comp.NotableFeatures = []string{"Indirect dependency"}
+			}
+			analysis.Components = append(analysis.Components, comp)
+			analysis.Relations = append(analysis.Relations, Relation{From: modulePath, To: fields[0], Type: "depends-on"})
+		}
+	}
+
+	analysis.Purpose = fmt.Sprintf("Go module definition for %s", modulePath)
+	if goVersion != "" {
+		analysis.Insights = append(analysis.Insights, fmt.Sprintf("Requires Go %s", goVersion))
+	}
+	analysis.Insights = append(analysis.Insights, fmt.Sprintf("%d direct and %d indirect dependencies", direct, indirect))
+
+	return analysis
+}
+
+var (
+	csharpUsingPattern     = regexp.MustCompile(`(?m)^\s*using\s+(?:static\s+)?([\w.]+)\s*;`)
+	csharpNamespacePattern = regexp.MustCompile(`(?m)^\s*namespace\s+([\w.]+)`)
+	csharpTypePattern      = regexp.MustCompile(`(?m)^\s*((?:(?:public|internal|private|protected|static|abstract|sealed|partial|readonly)\s+)*)(class|interface|struct|enum|record)\s+(\w+)(?:\s*<[^>]*>)?(?:\s*:\s*([\w.,<>\s]+?))?\s*(?:\{|$|where)`)
+	csharpSolutionPattern  = regexp.MustCompile(`(?m)^Project\("\{[^}]+\}"\)\s*=\s*"([^"]+)",\s*"([^"]+)"`)
+)
+
+// analyzeCSharpSource analyzes C# source code using declaration patterns
+func analyzeCSharpSource(file collector.FileInfo) *Analysis {
+	analysis := &Analysis{
+		Components: []Component{},
+		Relations:  []Relation{},
+		Insights:   []string{},
+	}
+
+	namespace := "global"
+	if m := csharpNamespacePattern.FindStringSubmatch(file.Content); m != nil {
+		namespace = m[1]
+	}
+
+	usings := csharpUsingPattern.FindAllStringSubmatch(file.Content, -1)
+	for _, m := range usings {
+		analysis.Relations = append(analysis.Relations, Relation{From: namespace, To: m[1], Type: "imports"})
+	}
+
+	for _, m := range csharpTypePattern.FindAllStringSubmatch(file.Content, -1) {
+		modifiers := strings.Fields(m[1])
+		visibility := "internal"
+		var features []string
+		for _, mod := range modifiers {
+			switch mod {
+			case "public", "internal", "private", "protected":
+				visibility = mod
+			default:
+				features = append(features, capitalize(mod))
+			}
+		}
+
+		analysis.Components = append(analysis.Components, Component{
+			Name:            m[3],
+			Type:            m[2],
+			Description:     fmt.Sprintf("%s %s in namespace %s", capitalize(m[2]), m[3], namespace),
+			Visibility:      visibility,
+			NotableFeatures: features,
+		})
+
+		for _, base := range strings.Split(m[4], ",") {
+			base = strings.TrimSpace(base)
+			if base == "" {
+				continue
+			}
+			relType := "extends"
+			if len(base) > 1 && base[0] == 'I' && base[1] >= 'A' && base[1] <= 'Z' {
+				relType = "implements"
+			}
+			analysis.Relations = append(analysis.Relations, Relation{From: m[3], To: base, Type: relType})
+		}
+	}
+
+	analysis.Purpose = fmt.Sprintf("C# source file in namespace %s declaring %d types", namespace, len(analysis.Components))
+	analysis.Insights = append(analysis.Insights, fmt.Sprintf("Uses %d namespaces", len(usings)))
+
+	return analysis
+}
+
+// csharpProject is the subset of an MSBuild project file we inspect
+type csharpProject struct {
+	SDK        string `xml:"Sdk,attr"`
+	ItemGroups []struct {
+		PackageReferences []struct {
+			Include string `xml:"Include,attr"`
+			Version string `xml:"Version,attr"`
+		} `xml:"PackageReference"`
+		ProjectReferences []struct {
+			Include string `xml:"Include,attr"`
+		} `xml:"ProjectReference"`
+	} `xml:"ItemGroup"`
+	PropertyGroups []struct {
+		TargetFramework string `xml:"TargetFramework"`
+		OutputType      string `xml:"OutputType"`
+	} `xml:"PropertyGroup"`
+}
+
+// analyzeCSharpProject analyzes a .csproj file
+func analyzeCSharpProject(file collector.FileInfo) (*Analysis, error) {
+	var project csharpProject
+	if err := xml.Unmarshal([]byte(file.Content), &project); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(file.Path), filepath.Ext(file.Path))
+	analysis := &Analysis{
+		Components: []Component{},
+		Relations:  []Relation{},
+		Insights:   []string{},
+	}
+
+	for _, group := range project.ItemGroups {
+		for _, pkg := range group.PackageReferences {
+			analysis.Components = append(analysis.Components, Component{
+				Name:        pkg.Include,
+				Type:        "package",
+				Description: fmt.Sprintf("NuGet package reference at version %s", pkg.Version),
+				Visibility:  "public",
+			})
+			analysis.Relations = append(analysis.Relations, Relation{From: name, To: pkg.Include, Type: "depends-on"})
+		}
+		for _, ref := range group.ProjectReferences {
+			target := strings.ReplaceAll(ref.Include, "\\", "/")
+			analysis.Relations = append(analysis.Relations, Relation{From: name, To: target, Type: "references"})
+		}
+	}
+
+	analysis.Purpose = fmt.Sprintf("C# project definition for %s", name)
+	for _, group := range project.PropertyGroups {
+		if group.TargetFramework != "" {
+			analysis.Insights = append(analysis.Insights, fmt.Sprintf("Targets %s", group.TargetFramework))
+		}
+		if group.OutputType != "" {
+			analysis.Insights = append(analysis.Insights, fmt.Sprintf("Produces a %s", group.OutputType))
+		}
+	}
+	if project.SDK != "" {
+		analysis.Insights = append(analysis.Insights, fmt.Sprintf("Uses the %s SDK", project.SDK))
+	}
+
+	return analysis, nil
+}
+
+// analyzeCSharpSolution analyzes a .sln file
+func analyzeCSharpSolution(file collector.FileInfo) *Analysis {
+	name := strings.TrimSuffix(filepath.Base(file.Path), filepath.Ext(file.Path))
+	analysis := &Analysis{
+		Components: []Component{},
+		Relations:  []Relation{},
+		Insights:   []string{},
+	}
+
+	for _, m := range csharpSolutionPattern.FindAllStringSubmatch(file.Content, -1) {
+		path := strings.ReplaceAll(m[2], "\\", "/")
+		analysis.Components = append(analysis.Components, Component{
+			Name:        m[1],
+			Type:        "project",
+			Description: fmt.Sprintf("Project located at %s", path),
+			Visibility:  "public",
+		})
+		analysis.Relations = append(analysis.Relations, Relation{From: name, To: path, Type: "contains"})
+	}
+
+	analysis.Purpose = fmt.Sprintf("C# solution %s containing %d projects", name, len(analysis.Components))
+	return analysis
+}
+
+// analyzeGeneric produces basic metrics for languages without a static analyzer
+func analyzeGeneric(file collector.FileInfo) *Analysis {
+	lines := strings.Split(file.Content, "\n")
+
+	purpose := leadingComment(lines)
+	if purpose == "" {
+		purpose = fmt.Sprintf("%s file %s", file.Language, filepath.Base(file.Path))
+	}
+
+	return &Analysis{
+		Purpose:    purpose,
+		Components: []Component{},
+		Relations:  []Relation{},
+		Insights: []string{
+			fmt.Sprintf("%d lines", len(lines)),
+			fmt.Sprintf("No static analyzer for %s; only basic metrics were collected", file.Language),
+		},
+	}
+}
+
+// leadingComment extracts the first sentence of a file's leading comment block
+func leadingComment(lines []string) string {
+	var text []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#!") || (line == "" && len(text) == 0) {
+			continue
+		}
+		if !isCommentLine(line) {
+			break
+		}
+		if trimmed := strings.TrimLeft(line, "/#*- "); trimmed != "" {
+			text = append(text, trimmed)
+		}
+	}
+	return firstSentence(strings.Join(text, " "))
+}
+
+// isCommentLine reports whether a line starts with a common comment marker
+func isCommentLine(line string) bool {
+	for _, marker := range []string{"//", "/*", "*", "#", "--"} {
+		if strings.HasPrefix(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstSentence returns the first sentence of a comment
+func firstSentence(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if i := strings.Index(text, ". "); i >= 0 {
+		return text[:i+1]
+	}
+	return text
+}
+
+// capitalize upper-cases the first letter of a word
+func capitalize(word string) string {
+	if word == "" {
+		return word
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+// pluralize adds an "s" to a word when count is not one
+func pluralize(word string, count int) string {
+	if count == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// sortRelations orders relations deterministically
+func sortRelations(relations []Relation) {
+	sort.SliceStable(relations, func(i, j int) bool {
+		if relations[i].From != relations[j].From {
+			return relations[i].From < relations[j].From
+		}
+		if relations[i].To != relations[j].To {
+			return relations[i].To < relations[j].To
+		}
+		return relations[i].Type < relations[j].Type
+	})
+}
@@ -0,0 +1,326 @@
+// autodoc/internal/analysis/static_test.go
+
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+)
+
+func TestStaticAnalyzerGo(t *testing.T) {
+	file := collector.FileInfo{
+		Path:     "store/store.go",
+		Language: "go",
+		Type:     "source",
+		Content: `// Package store keeps documents in memory. It is not persistent.
+package store
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Store holds documents.
+type Store struct {
+	sync.Mutex
+	docs map[string]string
+}
+
+// Getter reads documents.
+type Getter interface {
+	Get(id string) (string, error)
+}
+
+// Get returns a document.
+func (s *Store) Get(id string) (string, error) {
+	return lookup(s.docs, id)
+}
+
+func lookup(docs map[string]string, id string) (string, error) {
+	if doc, ok := docs[id]; ok {
+		return doc, nil
+	}
+	return "", fmt.Errorf("not found: %s", id)
+}
+`,
+	}
+
+	analysis, raw, err := NewStaticAnalyzer().AnalyzeFile(context.Background(), file)
+	if err != nil {
+		t.Fatalf("Failed to analyze file: %v", err)
+	}
+	if raw == "" {
+		t.Error("Expected a raw JSON response")
+	}
+
+	if analysis.Purpose != "Package store keeps documents in memory." {
+		t.Errorf("Unexpected purpose: %s", analysis.Purpose)
+	}
+
+	components := make(map[string]Component)
+	for _, comp := range analysis.Components {
+		components[comp.Name] = comp
+	}
+
+	expected := map[string]string{
+		"Store":     "struct",
+		"Getter":    "interface",
+		"Store.Get": "method",
+		"lookup":    "function",
+	}
+	for name, kind := range expected {
+		comp, ok := components[name]
+		if !ok {
+			t.Errorf("Expected component %s", name)
+			continue
+		}
+		if comp.Type != kind {
+			t.Errorf("Expected %s to be a %s, got %s", name, kind, comp.Type)
+		}
+	}
+
+	if vis := components["lookup"].Visibility; vis != "private" {
+		t.Errorf("Expected lookup to be private, got %s", vis)
+	}
+	if deps := components["lookup"].Dependencies; len(deps) != 1 || deps[0] != "fmt.Errorf" {
+		t.Errorf("Expected lookup to depend on fmt.Errorf, got %v", deps)
+	}
+
+	relations := make(map[Relation]bool)
+	for _, rel := range analysis.Relations {
+		relations[rel] = true
+	}
+	for _, rel := range []Relation{
+		{From: "Store.Get", To: "Store", Type: "method-of"},
+		{From: "Store.Get", To: "lookup", Type: "calls"},
+		{From: "Store", To: "sync.Mutex", Type: "embeds"},
+		{From: "store", To: "fmt", Type: "imports"},
+	} {
+		if !relations[rel] {
+			t.Errorf("Expected relation %+v, got %+v", rel, analysis.Relations)
+		}
+	}
+}
+
+func TestStaticAnalyzerGoMod(t *testing.T) {
+	file := collector.FileInfo{
+		Path:     "go.mod",
+		Language: "go",
+		Type:     "module",
+		Content: `module example.com/app
+
+go 1.23
+
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/sys v0.27.0 // indirect
+)
+`,
+	}
+
+	analysis, _, err := NewStaticAnalyzer().AnalyzeFile(context.Background(), file)
+	if err != nil {
+		t.Fatalf("Failed to analyze go.mod: %v", err)
+	}
+	if analysis.Purpose != "Go module definition for example.com/app" {
+		t.Errorf("Unexpected purpose: %s", analysis.Purpose)
+	}
+	if len(analysis.Components) != 2 {
+		t.Errorf("Expected 2 dependencies, got %d", len(analysis.Components))
+	}
+}
+
+func TestStaticAnalyzerGoImports(t *testing.T) {
+	file := collector.FileInfo{
+		Path:     "cmd/app/main.go",
+		Language: "go",
+		Type:     "source",
+		Content: `package main
+
+import (
+	_ "embed"
+	_ "net/http/pprof"
+	. "strings"
+
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
+	mux "github.com/gorilla/mux"
+)
+
+func run() error {
+	_ = ToUpper("x")
+	_ = redis.NewClient(nil)
+	_ = mux.NewRouter()
+	return yaml.Unmarshal(nil, nil)
+}
+`,
+	}
+
+	analysis, _, err := NewStaticAnalyzer().AnalyzeFile(context.Background(), file)
+	if err != nil {
+		t.Fatalf("Failed to analyze file: %v", err)
+	}
+
+	// Blank and dot imports are kept by path instead of overwriting each other
+	var imported []string
+	for _, rel := range analysis.Relations {
+		if rel.Type == "imports" {
+			imported = append(imported, rel.To)
+		}
+	}
+	expected := []string{"embed", "github.com/gorilla/mux", "github.com/redis/go-redis/v9", "gopkg.in/yaml.v3", "net/http/pprof", "strings"}
+	if strings.Join(imported, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected imports %v, got %v", expected, imported)
+	}
+
+	// Versioned paths resolve under their package name
+	for _, comp := range analysis.Components {
+		if comp.Name != "run" {
+			continue
+		}
+		deps := []string{"mux.NewRouter", "redis.NewClient", "yaml.Unmarshal"}
+		if strings.Join(comp.Dependencies, " ") != strings.Join(deps, " ") {
+			t.Errorf("Expected run to depend on %v, got %v", deps, comp.Dependencies)
+		}
+	}
+}
+
+func TestStaticAnalyzerCSharp(t *testing.T) {
+	file := collector.FileInfo{
+		Path:     "src/Orders/OrderService.cs",
+		Language: "csharp",
+		Type:     "source",
+		Content: `using System;
+using System.Collections.Generic;
+using static System.Math;
+
+namespace Shop.Orders
+{
+    public interface IOrderService
+    {
+        Order Find(int id);
+    }
+
+    public sealed class OrderService : ServiceBase, IOrderService, IDisposable
+    {
+        public Order Find(int id) => null;
+        public void Dispose() { }
+    }
+
+    internal record Order
+    {
+        public int Id { get; init; }
+    }
+}
+`,
+	}
+
+	analysis, _, err := NewStaticAnalyzer().AnalyzeFile(context.Background(), file)
+	if err != nil {
+		t.Fatalf("Failed to analyze file: %v", err)
+	}
+	if analysis.Purpose != "C# source file in namespace Shop.Orders declaring 3 types" {
+		t.Errorf("Unexpected purpose: %s", analysis.Purpose)
+	}
+
+	components := make(map[string]Component)
+	for _, comp := range analysis.Components {
+		components[comp.Name] = comp
+	}
+	if comp := components["OrderService"]; comp.Type != "class" || comp.Visibility != "public" || len(comp.NotableFeatures) != 1 || comp.NotableFeatures[0] != "Sealed" {
+		t.Errorf("Expected a public sealed class OrderService, got %+v", comp)
+	}
+	if comp := components["Order"]; comp.Type != "record" || comp.Visibility != "internal" {
+		t.Errorf("Expected an internal record Order, got %+v", comp)
+	}
+
+	relations := make(map[Relation]bool)
+	for _, rel := range analysis.Relations {
+		relations[rel] = true
+	}
+	for _, rel := range []Relation{
+		{From: "Shop.Orders", To: "System.Collections.Generic", Type: "imports"},
+		{From: "Shop.Orders", To: "System.Math", Type: "imports"},
+		{From: "OrderService", To: "ServiceBase", Type: "extends"},
+		{From: "OrderService", To: "IOrderService", Type: "implements"},
+		{From: "OrderService", To: "IDisposable", Type: "implements"},
+	} {
+		if !relations[rel] {
+			t.Errorf("Expected relation %+v, got %+v", rel, analysis.Relations)
+		}
+	}
+
+	// Only the using directives count as namespaces, not the base types
+	found := false
+	for _, insight := range analysis.Insights {
+		if insight == "Uses 3 namespaces" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the insight \"Uses 3 namespaces\", got %v", analysis.Insights)
+	}
+}
+
+func TestStaticAnalyzerCSharpProject(t *testing.T) {
+	project := collector.FileInfo{
+		Path:     "src/Orders/Orders.csproj",
+		Language: "csharp",
+		Type:     "project",
+		Content: `<Project Sdk="Microsoft.NET.Sdk">
+  <PropertyGroup>
+    <TargetFramework>net8.0</TargetFramework>
+    <OutputType>Exe</OutputType>
+  </PropertyGroup>
+  <ItemGroup>
+    <PackageReference Include="Newtonsoft.Json" Version="13.0.3" />
+    <ProjectReference Include="..\Shared\Shared.csproj" />
+  </ItemGroup>
+</Project>
+`,
+	}
+
+	analysis, _, err := NewStaticAnalyzer().AnalyzeFile(context.Background(), project)
+	if err != nil {
+		t.Fatalf("Failed to analyze project: %v", err)
+	}
+	if analysis.Purpose != "C# project definition for Orders" {
+		t.Errorf("Unexpected purpose: %s", analysis.Purpose)
+	}
+	if len(analysis.Components) != 1 || analysis.Components[0].Name != "Newtonsoft.Json" {
+		t.Errorf("Expected the Newtonsoft.Json package, got %+v", analysis.Components)
+	}
+	relations := make(map[Relation]bool)
+	for _, rel := range analysis.Relations {
+		relations[rel] = true
+	}
+	if !relations[Relation{From: "Orders", To: "../Shared/Shared.csproj", Type: "references"}] {
+		t.Errorf("Expected a reference to the Shared project, got %+v", analysis.Relations)
+	}
+
+	solution := collector.FileInfo{
+		Path:     "Shop.sln",
+		Language: "csharp",
+		Type:     "solution",
+		Content: `Microsoft Visual Studio Solution File, Format Version 12.00
+Project("{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}") = "Orders", "src\Orders\Orders.csproj", "{11111111-1111-1111-1111-111111111111}"
+EndProject
+Project("{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}") = "Shared", "src\Shared\Shared.csproj", "{22222222-2222-2222-2222-222222222222}"
+EndProject
+`,
+	}
+
+	analysis, _, err = NewStaticAnalyzer().AnalyzeFile(context.Background(), solution)
+	if err != nil {
+		t.Fatalf("Failed to analyze solution: %v", err)
+	}
+	if analysis.Purpose != "C# solution Shop containing 2 projects" {
+		t.Errorf("Unexpected purpose: %s", analysis.Purpose)
+	}
+	if len(analysis.Relations) != 2 || analysis.Relations[0].To != "src/Orders/Orders.csproj" {
+		t.Errorf("Expected 2 contained projects, got %+v", analysis.Relations)
+	}
+}
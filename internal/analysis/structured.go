@@ -0,0 +1,237 @@
+// autodoc/internal/analysis/structured.go
+
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
+
+// ValidationError reports a structured response that never passed validation
+type ValidationError struct {
+	Problems []string
+	Attempts int
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("response failed validation after %d attempts: %s", e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+// stringArraySchema is the JSON schema of a list of strings
+var stringArraySchema = map[string]any{
+	"type":  "array",
+	"items": map[string]any{"type": "string"},
+}
+
+// objectSchema builds a JSON schema object in which every property is required
+func objectSchema(properties map[string]any) map[string]any {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// AnalysisResponseFormat returns the JSON-schema response format for Analysis
+func AnalysisResponseFormat() *llm.ResponseFormat {
+	return &llm.ResponseFormat{
+		Name:   "code_analysis",
+		Strict: true,
+		Schema: objectSchema(map[string]any{
+			"purpose": map[string]any{"type": "string"},
+			"components": map[string]any{
+				"type": "array",
+				"items": objectSchema(map[string]any{
+					"name":             map[string]any{"type": "string"},
+					"type":             map[string]any{"type": "string"},
+					"description":      map[string]any{"type": "string"},
+					"visibility":       map[string]any{"type": "string"},
+					"dependencies":     stringArraySchema,
+					"notable_features": stringArraySchema,
+				}),
+			},
+			"relationships": map[string]any{
+				"type": "array",
+				"items": objectSchema(map[string]any{
+					"from": map[string]any{"type": "string"},
+					"to":   map[string]any{"type": "string"},
+					"type": map[string]any{"type": "string"},
+				}),
+			},
+			"insights": stringArraySchema,
+		}),
+	}
+}
+
+// CodeAnalysisResponseFormat returns the JSON-schema response format for
+// CodeAnalysisSchema. It is not strict because code quality metrics are free-form.
+func CodeAnalysisResponseFormat() *llm.ResponseFormat {
+	return &llm.ResponseFormat{
+		Name:   "architectural_analysis",
+		Strict: false,
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"architectural_patterns": stringArraySchema,
+				"code_quality_metrics":   map[string]any{"type": "object"},
+				"insights": map[string]any{
+					"type": "array",
+					"items": objectSchema(map[string]any{
+						"type":        map[string]any{"type": "string"},
+						"description": map[string]any{"type": "string"},
+						"impact":      map[string]any{"type": "string"},
+						"components":  stringArraySchema,
+					}),
+				},
+				"cross_references": map[string]any{
+					"type":                 "object",
+					"additionalProperties": stringArraySchema,
+				},
+			},
+			"required": []string{"architectural_patterns", "insights"},
+		},
+	}
+}
+
+// Validate checks that an Analysis carries the required fields
+func (a *Analysis) Validate() []string {
+	var problems []string
+	if strings.TrimSpace(a.Purpose) == "" {
+		problems = append(problems, `"purpose" must be a non-empty string`)
+	}
+	for i, comp := range a.Components {
+		if comp.Name == "" {
+			problems = append(problems, fmt.Sprintf(`components[%d] is missing "name"`, i))
+		}
+		if comp.Type == "" {
+			problems = append(problems, fmt.Sprintf(`components[%d] is missing "type"`, i))
+		}
+	}
+	for i, rel := range a.Relations {
+		if rel.From == "" || rel.To == "" || rel.Type == "" {
+			problems = append(problems, fmt.Sprintf(`relationships[%d] must have "from", "to" and "type"`, i))
+		}
+	}
+	return problems
+}
+
+// Validate checks that a CodeAnalysisSchema carries the required fields
+func (c *CodeAnalysisSchema) Validate() []string {
+	var problems []string
+	for i, insight := range c.Insights {
+		if insight.Type == "" || insight.Description == "" {
+			problems = append(problems, fmt.Sprintf(`insights[%d] must have "type" and "description"`, i))
+		}
+	}
+	return problems
+}
+
+// ExtractJSON pulls a JSON object out of a model response, tolerating
+// markdown code fences and prose before or after the object
+func ExtractJSON(raw string) string {
+	text := strings.TrimSpace(raw)
+
+	// Prefer the contents of a fenced code block
+	if start := strings.Index(text, "```"); start >= 0 {
+		body := text[start+3:]
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+			body = body[nl+1:] // Skip the language tag
+		}
+		if end := strings.Index(body, "```"); end >= 0 {
+			text = strings.TrimSpace(body[:end])
+		}
+	}
+
+	// Fall back to the outermost braces
+	if !strings.HasPrefix(text, "{") || !strings.HasSuffix(text, "}") {
+		start := strings.IndexByte(text, '{')
+		end := strings.LastIndexByte(text, '}')
+		if start >= 0 && end > start {
+			text = text[start : end+1]
+		}
+	}
+
+	return text
+}
+
+// missingFields returns the required top-level keys absent from a JSON object
+func missingFields(data string, required []string) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return nil
+	}
+	var missing []string
+	for _, name := range required {
+		if _, ok := fields[name]; !ok {
+			missing = append(missing, fmt.Sprintf(`missing required field %q`, name))
+		}
+	}
+	return missing
+}
+
+// validatable is implemented by structured responses
+type validatable interface {
+	Validate() []string
+}
+
+// requestStructured sends a chat request with a JSON-schema response format,
+// then extracts, decodes and validates the result. When validation fails the
+// model is shown its previous answer and the problems and asked to correct it,
+// up to repairAttempts more times. It returns the decoded value and the
+// extracted JSON of the accepted response.
+func requestStructured[T any, PT interface {
+	*T
+	validatable
+}](ctx context.Context, provider llm.Provider, messages []llm.Message, format *llm.ResponseFormat, repairAttempts int) (*T, string, error) {
+	required, _ := format.Schema["required"].([]string)
+
+	var lastRaw string
+	var problems []string
+	for attempt := 0; attempt <= repairAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, lastRaw, err
+		}
+		resp, err := provider.Chat(ctx, llm.ChatRequest{
+			Messages:       messages,
+			ResponseFormat: format,
+		})
+		if err != nil {
+			return nil, lastRaw, fmt.Errorf("LLM request failed: %w", err)
+		}
+
+		lastRaw = resp.Content
+		extracted := ExtractJSON(resp.Content)
+
+		value := PT(new(T))
+		if err := json.Unmarshal([]byte(extracted), value); err != nil {
+			problems = []string{fmt.Sprintf("response is not valid JSON: %v", err)}
+		} else {
+			problems = append(missingFields(extracted, required), value.Validate()...)
+		}
+
+		if len(problems) == 0 {
+			return (*T)(value), extracted, nil
+		}
+
+		// Ask the model to repair its previous answer
+		messages = append(messages,
+			llm.AssistantMessage(resp.Content),
+			llm.UserMessage(fmt.Sprintf("Your previous response was invalid:\n- %s\n\nReturn only the corrected JSON object, with no markdown formatting.",
+				strings.Join(problems, "\n- "))),
+		)
+	}
+
+	return nil, lastRaw, &ValidationError{Problems: problems, Attempts: repairAttempts + 1}
+}
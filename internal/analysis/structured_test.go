@@ -0,0 +1,139 @@
+// autodoc/internal/analysis/structured_test.go
+
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
+
+// scriptedProvider returns canned responses in order and records requests
+type scriptedProvider struct {
+	responses []string
+	requests  []llm.ChatRequest
+}
+
+func (s *scriptedProvider) Name() string  { return "scripted" }
+func (s *scriptedProvider) Model() string { return "scripted-model" }
+func (s *scriptedProvider) Usage() llm.Usage {
+	return llm.Usage{}
+}
+
+func (s *scriptedProvider) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	s.requests = append(s.requests, req)
+	if len(s.responses) == 0 {
+		return nil, errors.New("no scripted response left")
+	}
+	content := s.responses[0]
+	s.responses = s.responses[1:]
+	return &llm.ChatResponse{Content: content}, nil
+}
+
+func (s *scriptedProvider) Embed(ctx context.Context, inputs []string) (*llm.EmbeddingResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := map[string]string{
+		"plain":      `{"purpose":"x"}`,
+		"fenced":     "```json\n{\"purpose\":\"x\"}\n```",
+		"bare fence": "```\n{\"purpose\":\"x\"}\n```",
+		"prose":      "Here is the analysis:\n{\"purpose\":\"x\"}\nLet me know if you need more.",
+	}
+
+	for name, input := range tests {
+		if got := ExtractJSON(input); got != `{"purpose":"x"}` {
+			t.Errorf("%s: expected bare JSON object, got %q", name, got)
+		}
+	}
+}
+
+func TestAnalyzeFileRepairsInvalidResponse(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		// Fenced and missing "purpose"
+		"```json\n{\"components\": [], \"relationships\": [], \"insights\": []}\n```",
+		`{"purpose": "Adds numbers", "components": [], "relationships": [], "insights": []}`,
+	}}
+
+	a := NewAnalyzer(provider)
+	analysis, raw, err := a.AnalyzeFile(context.Background(), collector.FileInfo{
+		Path: "add.go", Language: "go", Type: "source", Content: "package add",
+	})
+	if err != nil {
+		t.Fatalf("Failed to analyze file: %v", err)
+	}
+	if analysis.Purpose != "Adds numbers" {
+		t.Errorf("Expected repaired purpose, got %q", analysis.Purpose)
+	}
+	if !strings.HasPrefix(raw, "{") {
+		t.Errorf("Expected extracted JSON as raw response, got %q", raw)
+	}
+
+	if len(provider.requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(provider.requests))
+	}
+	if provider.requests[0].ResponseFormat == nil {
+		t.Error("Expected a JSON-schema response format")
+	}
+	repair := provider.requests[1].Messages
+	if last := repair[len(repair)-1].Content; !strings.Contains(last, `"purpose"`) {
+		t.Errorf("Expected repair prompt to mention the missing field, got %q", last)
+	}
+}
+
+func TestAnalyzeFileGivesUpAfterRepairAttempts(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{"not json", "still not json"}}
+
+	a := NewAnalyzer(provider)
+	a.SetRepairAttempts(1)
+	_, raw, err := a.AnalyzeFile(context.Background(), collector.FileInfo{
+		Path: "add.go", Language: "go", Type: "source", Content: "package add",
+	})
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	if validationErr.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", validationErr.Attempts)
+	}
+	if raw != "still not json" {
+		t.Errorf("Expected last raw response, got %q", raw)
+	}
+}
+
+func TestAnalyzeFileIncludesDependencies(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		`{"purpose": "Serves pages", "components": [], "relationships": [], "insights": []}`,
+	}}
+	a := NewAnalyzer(provider)
+
+	file := collector.FileInfo{Path: "web/server.go", Language: "go", Type: "source", Content: "package web",
+		Dependencies: []collector.Dependency{
+			{Path: "internal/storage/badger.go", Purpose: "Stores documents in BadgerDB"},
+		},
+	}
+	if _, _, err := a.AnalyzeFile(context.Background(), file); err != nil {
+		t.Fatalf("Failed to analyze file: %v", err)
+	}
+
+	prompt := provider.requests[0].Messages[1].Content
+	if !strings.Contains(prompt, "- internal/storage/badger.go: Stores documents in BadgerDB") {
+		t.Errorf("Expected the dependency purpose in the prompt, got %q", prompt)
+	}
+}
+
+func TestFormatDependenciesTruncatesPurposes(t *testing.T) {
+	// A two-byte rune straddles the limit
+	purpose := strings.Repeat("a", maxPurposeLength-1) + "é" + "b"
+	got := FormatDependencies([]collector.Dependency{{Path: "a.go", Purpose: purpose}})
+	want := "- a.go: " + strings.Repeat("a", maxPurposeLength-1) + "...\n"
+	if got != want {
+		t.Errorf("Expected the purpose cut before the rune, got %q", got[len(got)-20:])
+	}
+}
@@ -5,124 +5,192 @@ package collector
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 )
 
-// FileInfo represents information about a source file
+// FileInfo represents information about a source file. Files from Stream
+// carry no content until Load is called.
 type FileInfo struct {
 	Path     string
 	Language string
 	Type     string
 	Content  string
+	Size     int64 // Size on disk, in bytes
+
+	// Dependencies are the documented files it imports, given to the
+	// analyzer as context
+	Dependencies []Dependency
+}
+
+// Dependency is an already documented file that another file imports
+type Dependency struct {
+	Path    string
+	Purpose string
+}
+
+// Load returns the file with its content read from disk, unless the content
+// is already loaded
+func (f FileInfo) Load() (FileInfo, error) {
+	if f.Content != "" {
+		return f, nil
+	}
+	content, err := os.ReadFile(f.Path)
+	if err != nil {
+		return f, fmt.Errorf("failed to read file %s: %w", f.Path, err)
+	}
+	f.Content = string(content)
+	return f, nil
+}
+
+// Matcher decides whether the file at path is collected and classifies it
+type Matcher func(path string) (language, fileType string, ok bool)
+
+// ClassifyPath is the default Matcher: it collects the files the Languages
+// registry recognizes
+func ClassifyPath(path string) (language, fileType string, ok bool) {
+	language, fileType = Languages.Classify(path)
+	return language, fileType, language != ""
 }
 
 // Collector handles repository cloning and file enumeration
 type Collector interface {
 	Clone(ctx context.Context, repoURL string) (string, error)
 	CollectFiles(ctx context.Context, path string) ([]FileInfo, error)
+	Stream(ctx context.Context, path string, match Matcher) (<-chan FileInfo, <-chan error)
 	ReadFile(path string) ([]byte, error)
 }
 
-// FSCollector implements the Collector interface for filesystem operations
-type FSCollector struct{}
+// FSCollector implements the Collector interface for filesystem operations.
+// Walks skip .git, the directories given to SetExclude and everything
+// ignored by .gitignore and .autodocignore files.
+type FSCollector struct {
+	exclude map[string]bool
+	clone   CloneOptions
+}
 
 // NewCollector initializes and returns a new Collector
-func NewCollector() Collector {
-	return &FSCollector{}
+func NewCollector() *FSCollector {
+	return &FSCollector{exclude: make(map[string]bool)}
 }
 
-// CollectFiles walks through the directory and collects relevant files
-func (c *FSCollector) CollectFiles(ctx context.Context, path string) ([]FileInfo, error) {
-	var files []FileInfo
-
-	err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+// SetExclude skips the given directories in every walk, such as the
+// documentation database kept inside the repository
+func (c *FSCollector) SetExclude(dirs ...string) {
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			c.exclude[abs] = true
 		}
+	}
+}
+
+// excluded reports whether dir was given to SetExclude
+func (c *FSCollector) excluded(dir string) bool {
+	if len(c.exclude) == 0 {
+		return false
+	}
+	abs, err := filepath.Abs(dir)
+	return err == nil && c.exclude[abs]
+}
 
+// CollectFiles walks through the directory and collects relevant files with
+// their content. Use Stream for large repositories.
+func (c *FSCollector) CollectFiles(ctx context.Context, path string) ([]FileInfo, error) {
+	var files []FileInfo
+	stream, errs := c.Stream(ctx, path, nil)
+	for file := range stream {
+		file, err := file.Load()
 		if err != nil {
-			if os.IsNotExist(err) {
-				return nil // Skip non-existent paths
-			}
-			if os.IsPermission(err) {
-				return nil // Skip inaccessible paths
+			// Drain the walk so it can finish
+			for range stream {
 			}
-			return err
+			return nil, err
 		}
+		files = append(files, file)
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return files, nil
+}
 
-		if !info.IsDir() {
-			ext := strings.ToLower(filepath.Ext(filePath))
-			language, fileType := classifyFile(ext)
+// Stream walks path in the background and sends each file accepted by match
+// on the returned channel, without its content, so only the files being
+// worked on are held in memory. A nil match uses ClassifyPath. The file
+// channel is closed when the walk ends; the error channel then yields the
+// walk error, if any. Cancelling ctx stops the walk with ctx.Err().
+func (c *FSCollector) Stream(ctx context.Context, path string, match Matcher) (<-chan FileInfo, <-chan error) {
+	if match == nil {
+		match = ClassifyPath
+	}
+	files := make(chan FileInfo)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(files)
+
+		ignore := newIgnoreTree(path)
+		err := filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
+			// Check context cancellation
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 
-			// Only collect files we're interested in
-			if language != "" {
-				content, err := c.ReadFile(filePath)
-				if err != nil {
-					return fmt.Errorf("failed to read file %s: %w", filePath, err)
+			if err != nil {
+				if filePath == path {
+					return err
+				}
+				if os.IsNotExist(err) || os.IsPermission(err) {
+					return nil // Skip missing and inaccessible paths
+				}
+				return err
+			}
+			if d.IsDir() {
+				if filePath != path && (d.Name() == ".git" || c.excluded(filePath) || ignore.ignored(filePath, true)) {
+					return filepath.SkipDir
 				}
+				ignore.enter(filePath)
+				return nil
+			}
+			if ignore.ignored(filePath, false) {
+				return nil
+			}
 
-				files = append(files, FileInfo{
-					Path:     filePath,
-					Language: language,
-					Type:     fileType,
-					Content:  string(content),
-				})
+			// Only collect files we're interested in
+			language, fileType, ok := match(filePath)
+			if !ok {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil // Removed since it was listed
 			}
-		}
-		return nil
-	})
 
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("path does not exist: %s", path)
+			select {
+			case files <- FileInfo{Path: filePath, Language: language, Type: fileType, Size: info.Size()}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+
+		switch {
+		case err == nil:
+		case ctx.Err() != nil:
+			errs <- ctx.Err()
+		case os.IsNotExist(err):
+			errs <- fmt.Errorf("path does not exist: %s", path)
+		default:
+			errs <- fmt.Errorf("error walking path %s: %w", path, err)
 		}
-		return nil, fmt.Errorf("error walking path %s: %w", path, err)
-	}
+	}()
 
-	return files, nil
+	return files, errs
 }
 
 // ReadFile reads the content of the specified file
 func (c *FSCollector) ReadFile(path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
-
-// Clone clones the repository from the given URL to a temporary directory
-func (c *FSCollector) Clone(ctx context.Context, repoURL string) (string, error) {
-	tempDir, err := os.MkdirTemp("", "repo-*")
-	if err != nil {
-		return "", fmt.Errorf("failed to create temp directory: %w", err)
-	}
-
-	cmd := exec.CommandContext(ctx, "git", "clone", repoURL, tempDir)
-	if err := cmd.Run(); err != nil {
-		os.RemoveAll(tempDir) // Clean up on error
-		return "", fmt.Errorf("failed to clone repository: %w", err)
-	}
-
-	return tempDir, nil
-}
-
-// classifyFile determines the language and type of a file based on its extension
-func classifyFile(ext string) (language, fileType string) {
-	switch ext {
-	case ".go":
-		return "go", "source"
-	case ".cs":
-		return "csharp", "source"
-	case ".csproj":
-		return "csharp", "project"
-	case ".sln":
-		return "csharp", "solution"
-	case ".mod":
-		return "go", "module"
-	default:
-		return "", ""
-	}
-}
@@ -0,0 +1,99 @@
+// autodoc/internal/collector/collector_test.go
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles creates the given files under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+func TestStreamLoadsContentLazily(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":        "package main\n",
+		"lib/lib.go":     "package lib\n",
+		"README.md":      "# Readme\n",
+		"app/App.csproj": "<Project />\n",
+	})
+
+	c := NewCollector()
+	stream, errs := c.Stream(context.Background(), dir, nil)
+	var files []FileInfo
+	for file := range stream {
+		if file.Content != "" {
+			t.Errorf("Expected %s to be streamed without content", file.Path)
+		}
+		files = append(files, file)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Failed to stream files: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 files, got %d", len(files))
+	}
+
+	for _, file := range files {
+		if file.Path != filepath.Join(dir, "main.go") {
+			continue
+		}
+		if file.Language != "go" || file.Size != int64(len("package main\n")) {
+			t.Errorf("Expected a Go file of %d bytes, got %+v", len("package main\n"), file)
+		}
+		loaded, err := file.Load()
+		if err != nil {
+			t.Fatalf("Failed to load file: %v", err)
+		}
+		if loaded.Content != "package main\n" {
+			t.Errorf("Expected content to be loaded, got %q", loaded.Content)
+		}
+	}
+}
+
+func TestStreamStopsWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	files := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		files[fmt.Sprintf("f%02d.go", i)] = "package f\n"
+	}
+	writeFiles(t, dir, files)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, errs := NewCollector().Stream(ctx, dir, nil)
+	<-stream
+	cancel()
+	received := 1
+	for range stream {
+		received++
+	}
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if received >= 50 {
+		t.Errorf("Expected the walk to stop early, got all %d files", received)
+	}
+}
+
+func TestCollectFilesMissingPath(t *testing.T) {
+	_, err := NewCollector().CollectFiles(context.Background(), filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
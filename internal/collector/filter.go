@@ -0,0 +1,138 @@
+// autodoc/internal/collector/filter.go
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Reasons a Filter skips a file
+const (
+	SkipNotIncluded = "not matched by an include pattern"
+	SkipExcluded    = "matched by an exclude pattern"
+	SkipTooLarge    = "larger than the size limit"
+	SkipBinary      = "binary content"
+	SkipGenerated   = "generated code"
+	SkipUnreadable  = "unreadable"
+)
+
+// sniffSize is how much of a file is read to detect binary or generated content
+const sniffSize = 8 << 10
+
+// generatedPattern matches the standard generated-code markers at the start
+// of a comment line: Go's "Code generated ... DO NOT EDIT." header, and the
+// auto-generated tag and generated annotation other toolchains write. Lines
+// may end in CRLF, as in Windows checkouts.
+var generatedPattern = regexp.MustCompile(`(?m)^\s*(?://|#|/\*|\*)?\s*(?:Code generated .* DO NOT EDIT\.\r?$|<auto-generated|@generated\b)`)
+
+// Skipped is a file a Filter left out, with the reason
+type Skipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Filter selects the collected files worth documenting. Include and
+// exclude patterns use the gitignore syntax, relative to the root; a
+// pattern matching a directory applies to every file below it.
+type Filter struct {
+	include          IgnoreRules
+	exclude          IgnoreRules
+	maxSize          int64
+	includeGenerated bool
+}
+
+// NewFilter creates a filter from include and exclude globs. A file must
+// match an include glob when there are any, and may match no exclude glob.
+func NewFilter(include, exclude []string) *Filter {
+	return &Filter{
+		include: ParseIgnore("", strings.Join(include, "\n")),
+		exclude: ParseIgnore("", strings.Join(exclude, "\n")),
+	}
+}
+
+// SetMaxSize skips files larger than size bytes; 0 removes the limit
+func (f *Filter) SetMaxSize(size int64) {
+	f.maxSize = size
+}
+
+// SetIncludeGenerated keeps files marked as generated code
+func (f *Filter) SetIncludeGenerated(include bool) {
+	f.includeGenerated = include
+}
+
+// Check returns why file, found under root, should be skipped, or "" to keep
+// it. Patterns and size are checked first; only then is the start of the
+// file read to detect binary and generated content. A nil Filter keeps
+// every file.
+func (f *Filter) Check(root string, file FileInfo) string {
+	if f == nil {
+		return ""
+	}
+
+	rel, err := filepath.Rel(root, file.Path)
+	if err != nil {
+		rel = file.Path
+	}
+	rel = filepath.ToSlash(rel)
+	if len(f.include) > 0 && !matchesPath(f.include, rel) {
+		return SkipNotIncluded
+	}
+	if matchesPath(f.exclude, rel) {
+		return SkipExcluded
+	}
+	if f.maxSize > 0 && file.Size > f.maxSize {
+		return fmt.Sprintf("%s (%d bytes)", SkipTooLarge, file.Size)
+	}
+
+	head, err := sniff(file)
+	if err != nil {
+		return SkipUnreadable
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return SkipBinary
+	}
+	if !f.includeGenerated && generatedPattern.Match(head) {
+		return SkipGenerated
+	}
+	return ""
+}
+
+// matchesPath reports whether rules match the file rel or one of its
+// directories, so "vendor/" selects everything below vendor
+func matchesPath(rules IgnoreRules, rel string) bool {
+	if rules.Ignored(rel, false) {
+		return true
+	}
+	for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if rules.Ignored(dir, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniff returns the start of file's content
+func sniff(file FileInfo) ([]byte, error) {
+	if file.Content != "" {
+		return []byte(file.Content[:min(len(file.Content), sniffSize)]), nil
+	}
+	fh, err := os.Open(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(fh, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
@@ -0,0 +1,78 @@
+// autodoc/internal/collector/filter_test.go
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilterCheck(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":             "package main\n",
+		"api/api.pb.go":       "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n",
+		"api/handwritten.go":  "package api\n\n// Code generated by hand is still ours.\n",
+		"api/windows.pb.go":   "// Code generated by protoc-gen-go. DO NOT EDIT.\r\n\r\npackage api\r\n",
+		"Form.Designer.cs":    "//------\n// <auto-generated>\n//------\nclass Form {}\n",
+		"logo.go":             "package main\x00\x01",
+		"big.go":              "package main\n" + strings.Repeat("// filler\n", 20),
+		"vendor/lib/lib.go":   "package lib\n",
+		"testdata/fixture.go": "package testdata\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	filter := NewFilter([]string{"*.go", "*.cs"}, []string{"vendor/", "testdata/**"})
+	filter.SetMaxSize(100)
+
+	tests := []struct {
+		name   string
+		reason string
+	}{
+		{"main.go", ""},
+		{"api/api.pb.go", SkipGenerated},
+		{"api/handwritten.go", ""},
+		{"api/windows.pb.go", SkipGenerated},
+		{"Form.Designer.cs", SkipGenerated},
+		{"logo.go", SkipBinary},
+		{"big.go", SkipTooLarge},
+		{"vendor/lib/lib.go", SkipExcluded},
+		{"testdata/fixture.go", SkipExcluded},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, filepath.FromSlash(tt.name))
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", tt.name, err)
+		}
+		reason := filter.Check(dir, FileInfo{Path: path, Size: info.Size()})
+		if !strings.HasPrefix(reason, tt.reason) || (tt.reason == "" && reason != "") {
+			t.Errorf("Expected %s to be skipped for %q, got %q", tt.name, tt.reason, reason)
+		}
+	}
+
+	if reason := filter.Check(dir, FileInfo{Path: filepath.Join(dir, "README.md")}); reason != SkipNotIncluded {
+		t.Errorf("Expected README.md to be skipped for %q, got %q", SkipNotIncluded, reason)
+	}
+
+	filter.SetIncludeGenerated(true)
+	path := filepath.Join(dir, "api", "api.pb.go")
+	if reason := filter.Check(dir, FileInfo{Path: path}); reason != "" {
+		t.Errorf("Expected generated code to be kept, got %q", reason)
+	}
+
+	var none *Filter
+	if reason := none.Check(dir, FileInfo{Path: path}); reason != "" {
+		t.Errorf("Expected a nil filter to keep every file, got %q", reason)
+	}
+}
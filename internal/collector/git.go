@@ -0,0 +1,122 @@
+// autodoc/internal/collector/git.go
+
+package collector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// CloneOptions controls what Clone checks out
+type CloneOptions struct {
+	Ref         string   // Branch, tag or commit to check out (the default branch when empty)
+	Depth       int      // Number of commits fetched (0 for the full history)
+	SparsePaths []string // Directories checked out, in cone mode (every file when empty)
+	Submodules  bool     // Check out submodules recursively
+}
+
+// SetCloneOptions sets the ref, depth, sparse paths and submodule handling
+// of later clones
+func (c *FSCollector) SetCloneOptions(opts CloneOptions) {
+	c.clone = opts
+}
+
+// Clone checks out the repository at repoURL into a new temporary
+// directory, which the caller removes once done with it. The repository is
+// fetched into an empty one so that any ref, including a commit, can be
+// checked out at any depth.
+func (c *FSCollector) Clone(ctx context.Context, repoURL string) (string, error) {
+	// Values starting with a dash would be read by git as options
+	if strings.HasPrefix(repoURL, "-") {
+		return "", fmt.Errorf("invalid repository URL %q", repoURL)
+	}
+	if strings.HasPrefix(c.clone.Ref, "-") {
+		return "", fmt.Errorf("invalid ref %q", c.clone.Ref)
+	}
+
+	tempDir, err := os.MkdirTemp("", "repo-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	if err := c.checkout(ctx, tempDir, repoURL); err != nil {
+		os.RemoveAll(tempDir) // Clean up on error
+		return "", fmt.Errorf("failed to clone repository: %w", err)
+	}
+	return tempDir, nil
+}
+
+// checkout fetches the configured ref of repoURL into dir and checks it out
+func (c *FSCollector) checkout(ctx context.Context, dir, repoURL string) error {
+	opts := c.clone
+	if err := git(ctx, dir, "init", "--quiet"); err != nil {
+		return err
+	}
+	if err := git(ctx, dir, "remote", "add", "--", "origin", repoURL); err != nil {
+		return err
+	}
+	if len(opts.SparsePaths) > 0 {
+		args := append([]string{"sparse-checkout", "set", "--cone", "--"}, opts.SparsePaths...)
+		if err := git(ctx, dir, args...); err != nil {
+			return err
+		}
+	}
+
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	fetch := []string{"fetch", "--quiet"}
+	if opts.Depth > 0 {
+		fetch = append(fetch, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if err := git(ctx, dir, append(fetch, "--", "origin", ref)...); err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", ref, err)
+	}
+	if err := git(ctx, dir, "checkout", "--quiet", "--detach", "FETCH_HEAD"); err != nil {
+		return err
+	}
+
+	if opts.Submodules {
+		update := []string{"submodule", "update", "--init", "--recursive"}
+		if opts.Depth > 0 {
+			update = append(update, "--depth", strconv.Itoa(opts.Depth))
+		}
+		if err := git(ctx, dir, update...); err != nil {
+			return fmt.Errorf("failed to check out submodules: %w", err)
+		}
+	}
+	return nil
+}
+
+// HeadCommit returns the SHA of the commit checked out in the repository at
+// dir, or an error when dir is not a git work tree
+func HeadCommit(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve commit of %s: %w", dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// git runs a git command in dir, including its output in the error
+func git(ctx context.Context, dir string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// autodoc/internal/collector/git_test.go
+
+package collector
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// runGit runs a git command in dir and returns its trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to run git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// bareRepo creates a bare repository from commits of files, tagging the
+// first commit v1, and returns its path and the SHAs of the commits
+func bareRepo(t *testing.T, commits ...map[string]string) (string, []string) {
+	t.Helper()
+	work := t.TempDir()
+	runGit(t, work, "init", "--quiet", "--initial-branch=main")
+	var shas []string
+	for _, files := range commits {
+		writeFiles(t, work, files)
+		runGit(t, work, "add", "--all")
+		runGit(t, work, "commit", "--quiet", "--message", "commit")
+		shas = append(shas, runGit(t, work, "rev-parse", "HEAD"))
+	}
+	runGit(t, work, "tag", "v1", shas[0])
+
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	runGit(t, work, "clone", "--quiet", "--bare", work, bare)
+	return bare, shas
+}
+
+func TestClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	bare, shas := bareRepo(t,
+		map[string]string{"main.go": "package main\n", "lib/lib.go": "package lib\n"},
+		map[string]string{"docs/guide.go": "package docs\n"},
+		map[string]string{"lib/more.go": "package lib\n"},
+	)
+
+	tests := []struct {
+		name    string
+		opts    CloneOptions
+		commit  string
+		present []string
+		absent  []string
+		history int
+	}{
+		{"default branch", CloneOptions{}, shas[2], []string{"main.go", "docs/guide.go", "lib/more.go"}, nil, 3},
+		{"tag", CloneOptions{Ref: "v1"}, shas[0], []string{"lib/lib.go"}, []string{"docs/guide.go"}, 1},
+		{"commit", CloneOptions{Ref: shas[1]}, shas[1], []string{"docs/guide.go"}, []string{"lib/more.go"}, 2},
+		{"shallow", CloneOptions{Ref: "main", Depth: 1}, shas[2], []string{"lib/more.go"}, nil, 1},
+		{"sparse", CloneOptions{SparsePaths: []string{"lib"}}, shas[2], []string{"main.go", "lib/more.go"}, []string{"docs/guide.go"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCollector()
+			c.SetCloneOptions(tt.opts)
+			dir, err := c.Clone(context.Background(), "file://"+bare)
+			if err != nil {
+				t.Fatalf("Failed to clone repository: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			commit, err := HeadCommit(context.Background(), dir)
+			if err != nil {
+				t.Fatalf("Failed to resolve commit: %v", err)
+			}
+			if commit != tt.commit {
+				t.Errorf("Expected commit %s, got %s", tt.commit, commit)
+			}
+			for _, name := range tt.present {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("Expected %s to be checked out: %v", name, err)
+				}
+			}
+			for _, name := range tt.absent {
+				if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+					t.Errorf("Expected %s not to be checked out", name)
+				}
+			}
+			if history := runGit(t, dir, "rev-list", "--count", "HEAD"); history != strconv.Itoa(tt.history) {
+				t.Errorf("Expected %d commits of history, got %s", tt.history, history)
+			}
+		})
+	}
+}
+
+func TestCloneSubmodules(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// Local submodule URLs are refused unless the file protocol is allowed
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	sub, _ := bareRepo(t, map[string]string{"sub.go": "package sub\n"})
+	work := t.TempDir()
+	runGit(t, work, "init", "--quiet", "--initial-branch=main")
+	writeFiles(t, work, map[string]string{"main.go": "package main\n"})
+	runGit(t, work, "submodule", "--quiet", "add", "file://"+sub, "vendor/sub")
+	runGit(t, work, "add", "--all")
+	runGit(t, work, "commit", "--quiet", "--message", "with submodule")
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	runGit(t, work, "clone", "--quiet", "--bare", work, bare)
+
+	for _, submodules := range []bool{false, true} {
+		c := NewCollector()
+		c.SetCloneOptions(CloneOptions{Submodules: submodules})
+		dir, err := c.Clone(context.Background(), "file://"+bare)
+		if err != nil {
+			t.Fatalf("Failed to clone repository: %v", err)
+		}
+		_, err = os.Stat(filepath.Join(dir, "vendor", "sub", "sub.go"))
+		if submodules != (err == nil) {
+			t.Errorf("Expected submodule checked out to be %v, got error %v", submodules, err)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCloneFailureRemovesDirectory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	c := NewCollector()
+	c.SetCloneOptions(CloneOptions{Ref: "no-such-branch"})
+	bare, _ := bareRepo(t, map[string]string{"main.go": "package main\n"})
+	if _, err := c.Clone(context.Background(), "file://"+bare); err == nil {
+		t.Fatalf("Expected cloning a missing ref to fail")
+	}
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatalf("Failed to read temp directory: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "repo-") {
+			t.Errorf("Expected the clone directory to be removed, found %s", entry.Name())
+		}
+	}
+
+	if _, err := HeadCommit(context.Background(), t.TempDir()); err == nil {
+		t.Errorf("Expected resolving the commit of a plain directory to fail")
+	}
+}
+
+func TestCloneRejectsOptions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	marker := filepath.Join(t.TempDir(), "pwned")
+	bare, _ := bareRepo(t, map[string]string{"main.go": "package main\n"})
+
+	tests := []struct {
+		name string
+		url  string
+		ref  string
+	}{
+		{"url", "--upload-pack=touch " + marker, ""},
+		{"ref", "file://" + bare, "--upload-pack=touch " + marker},
+	}
+	for _, tt := range tests {
+		c := NewCollector()
+		c.SetCloneOptions(CloneOptions{Ref: tt.ref})
+		if _, err := c.Clone(context.Background(), tt.url); err == nil {
+			t.Errorf("Expected an option-like %s to be rejected", tt.name)
+		}
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("Expected no option to reach git")
+	}
+	if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("Expected no clone directory to be created, found %d entries", len(entries))
+	}
+}
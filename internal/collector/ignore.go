@@ -0,0 +1,190 @@
+// autodoc/internal/collector/ignore.go
+
+package collector
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFiles are the files whose patterns are honored in every directory.
+// .autodocignore is read after .gitignore, so it can re-include files that
+// are only ignored by git.
+var IgnoreFiles = []string{".gitignore", ".autodocignore"}
+
+// ignoreRule is one pattern of an ignore file
+type ignoreRule struct {
+	base    string // Slash path of the ignore file's directory, relative to the root
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreRules is an ordered list of gitignore patterns; later rules take
+// precedence over earlier ones
+type IgnoreRules []ignoreRule
+
+// ParseIgnore parses gitignore-style patterns from an ignore file in the
+// directory base, a slash path relative to the root ("" for the root)
+func ParseIgnore(base, content string) IgnoreRules {
+	var rules IgnoreRules
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if rule, ok := parseIgnoreLine(base, line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseIgnoreLine parses one pattern, skipping blank lines and comments
+func parseIgnoreLine(base, line string) (ignoreRule, bool) {
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// Patterns with a slash are relative to the ignore file; others match
+	// a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = re
+	return rule, true
+}
+
+// globToRegexp translates a gitignore glob into a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**") && i+2 == len(glob) && (i == 0 || glob[i-1] == '/'):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Ignored reports whether the slash path rel, relative to the root, is
+// ignored. The last matching rule decides. Files inside an ignored
+// directory are not matched here; walks skip such directories instead.
+func (r IgnoreRules) Ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range r {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		target := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			target = rel[len(rule.base)+1:]
+		}
+		if rule.pattern.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// ignoreTree loads the ignore files of the directories of one walk
+type ignoreTree struct {
+	root  string
+	rules map[string]IgnoreRules // Rules in effect in each visited directory
+}
+
+// newIgnoreTree starts a tree at root, reading .git/info/exclude as well
+func newIgnoreTree(root string) *ignoreTree {
+	t := &ignoreTree{root: root, rules: make(map[string]IgnoreRules)}
+	if content, err := os.ReadFile(filepath.Join(root, ".git", "info", "exclude")); err == nil {
+		t.rules[""] = ParseIgnore("", string(content))
+	}
+	return t
+}
+
+// rel returns the slash path of p relative to the root
+func (t *ignoreTree) rel(p string) string {
+	rel, err := filepath.Rel(t.root, p)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// enter loads the ignore files of dir, which must be visited after its parent
+func (t *ignoreTree) enter(dir string) {
+	rel := t.rel(dir)
+	rules := t.rules[rel]
+	if rel != "" {
+		rules = append(IgnoreRules(nil), t.rules[path.Dir("/" + rel)[1:]]...)
+	}
+	for _, name := range IgnoreFiles {
+		if content, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			rules = append(rules, ParseIgnore(rel, string(content))...)
+		}
+	}
+	t.rules[rel] = rules
+}
+
+// ignored reports whether the entry p inside a visited directory is ignored
+func (t *ignoreTree) ignored(p string, isDir bool) bool {
+	rel := t.rel(p)
+	if rel == "" {
+		return false
+	}
+	return t.rules[path.Dir("/" + rel)[1:]].Ignored(rel, isDir)
+}
@@ -0,0 +1,91 @@
+// autodoc/internal/collector/ignore_test.go
+
+package collector
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := ParseIgnore("", strings.Join([]string{
+		"# build output",
+		"*.log",
+		"!keep.log",
+		"/build",
+		"node_modules/",
+		"docs/**/draft.md",
+		"gen/**",
+		`\#notes`,
+		"tmp?.go",
+		"[ab].go",
+	}, "\n"))
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"debug.log", false, true},
+		{"sub/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"sub/build", true, false}, // Anchored to the root
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"node_modules", false, false}, // Directories only
+		{"docs/draft.md", false, true},
+		{"docs/a/b/draft.md", false, true},
+		{"gen/x/y.go", false, true},
+		{"gen", true, false},
+		{"#notes", false, true},
+		{"tmp1.go", false, true},
+		{"tmp12.go", false, false},
+		{"a.go", false, true},
+		{"c.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := rules.Ignored(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("Expected Ignored(%q, %v) to be %v, got %v", tt.path, tt.isDir, tt.ignored, got)
+		}
+	}
+}
+
+func TestStreamHonorsIgnoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore":              "vendor/\n*_gen.go\nstorage/\n",
+		".autodocignore":          "internal/legacy/\n!api_gen.go\n",
+		"main.go":                 "package main\n",
+		"api_gen.go":              "package main\n",
+		"db_gen.go":               "package main\n",
+		"vendor/lib/lib.go":       "package lib\n",
+		"internal/legacy/old.go":  "package legacy\n",
+		"internal/web/.gitignore": "*.go\n!server.go\n",
+		"internal/web/server.go":  "package web\n",
+		"internal/web/helper.go":  "package web\n",
+		".git/hooks/hook.go":      "package hooks\n",
+		"db/MANIFEST.go":          "package db\n",
+	})
+
+	c := NewCollector()
+	c.SetExclude(filepath.Join(dir, "db"))
+	files, err := c.CollectFiles(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Failed to collect files: %v", err)
+	}
+
+	var got []string
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file.Path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	want := "api_gen.go,internal/web/server.go,main.go"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(got, ","))
+	}
+}
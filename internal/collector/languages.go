@@ -0,0 +1,238 @@
+// autodoc/internal/collector/languages.go
+
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Language describes how the files of one language are recognized
+type Language struct {
+	Name         string            // Name used for prompts and documents, e.g. "csharp"
+	Title        string            // Display name, e.g. "C#"
+	Extensions   []string          // Extensions of source files, with the dot
+	Files        map[string]string // File names, or extensions with the dot, of files of another type, to that type
+	Interpreters []string          // Shebang interpreters of scripts, e.g. "python3"
+}
+
+// Languages is the registry every collector, analyzer and prompt lookup
+// classifies files with
+var Languages = NewRegistry(
+	Language{Name: "go", Title: "Go", Extensions: []string{".go"},
+		Files: map[string]string{"go.mod": "module", "go.work": "module"}},
+	Language{Name: "csharp", Title: "C#", Extensions: []string{".cs"},
+		Files: map[string]string{".csproj": "project", ".sln": "solution"}},
+	Language{Name: "javascript", Title: "JavaScript", Extensions: []string{".js", ".mjs", ".cjs", ".jsx"},
+		Files: map[string]string{"package.json": "project"}, Interpreters: []string{"node"}},
+	Language{Name: "typescript", Title: "TypeScript", Extensions: []string{".ts", ".mts", ".cts", ".tsx"},
+		Files: map[string]string{"tsconfig.json": "project"}, Interpreters: []string{"ts-node", "deno"}},
+	Language{Name: "python", Title: "Python", Extensions: []string{".py"},
+		Files: map[string]string{"pyproject.toml": "project"}, Interpreters: []string{"python", "python3"}},
+	Language{Name: "rust", Title: "Rust", Extensions: []string{".rs"},
+		Files: map[string]string{"Cargo.toml": "project"}},
+	Language{Name: "java", Title: "Java", Extensions: []string{".java"},
+		Files: map[string]string{"pom.xml": "project", "build.gradle": "project"}},
+	Language{Name: "ruby", Title: "Ruby", Extensions: []string{".rb"},
+		Files: map[string]string{"Gemfile": "project"}, Interpreters: []string{"ruby"}},
+	Language{Name: "c", Title: "C", Extensions: []string{".c", ".h"}},
+	Language{Name: "cpp", Title: "C++", Extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hh"}},
+	Language{Name: "shell", Title: "Shell", Extensions: []string{".sh", ".bash"},
+		Interpreters: []string{"sh", "bash", "zsh"}},
+	Language{Name: "docker", Title: "Dockerfile",
+		Files: map[string]string{"Dockerfile": "build", "Containerfile": "build"}},
+	Language{Name: "make", Title: "Makefile", Extensions: []string{".mk"},
+		Files: map[string]string{"Makefile": "build", "makefile": "build", "GNUmakefile": "build"}},
+)
+
+// classification is the language and file type of a kind of file
+type classification struct {
+	language string
+	fileType string
+}
+
+// Registry maps extensions, well-known file names and shebang interpreters
+// to languages. Extensions are matched case-insensitively, file names exactly.
+type Registry struct {
+	languages    map[string]Language
+	order        []string
+	extensions   map[string]classification
+	names        map[string]classification
+	interpreters map[string]string
+}
+
+// NewRegistry creates a registry of the given languages
+func NewRegistry(languages ...Language) *Registry {
+	r := &Registry{
+		languages:    make(map[string]Language),
+		extensions:   make(map[string]classification),
+		names:        make(map[string]classification),
+		interpreters: make(map[string]string),
+	}
+	for _, lang := range languages {
+		r.Register(lang)
+	}
+	return r
+}
+
+// Register adds lang to the registry, taking over the extensions, file
+// names and interpreters already claimed by another language
+func (r *Registry) Register(lang Language) {
+	if _, ok := r.languages[lang.Name]; !ok {
+		r.order = append(r.order, lang.Name)
+	}
+	r.languages[lang.Name] = lang
+	for _, ext := range lang.Extensions {
+		r.extensions[strings.ToLower(ext)] = classification{lang.Name, "source"}
+	}
+	for name, fileType := range lang.Files {
+		if strings.HasPrefix(name, ".") {
+			r.extensions[strings.ToLower(name)] = classification{lang.Name, fileType}
+		} else {
+			r.names[name] = classification{lang.Name, fileType}
+		}
+	}
+	for _, interpreter := range lang.Interpreters {
+		r.interpreters[interpreter] = lang.Name
+	}
+}
+
+// Language returns the registered language called name
+func (r *Registry) Language(name string) (Language, bool) {
+	lang, ok := r.languages[name]
+	return lang, ok
+}
+
+// Names returns the names of the registered languages in registration order
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.order...)
+}
+
+// Title returns the display name of the language called name, or name
+// itself for unregistered languages
+func (r *Registry) Title(name string) string {
+	if lang, ok := r.languages[name]; ok && lang.Title != "" {
+		return lang.Title
+	}
+	return name
+}
+
+// ClassifyExtension returns the language and file type of files with the
+// extension ext, including the dot, or empty strings for unknown ones
+func (r *Registry) ClassifyExtension(ext string) (language, fileType string) {
+	c := r.extensions[strings.ToLower(ext)]
+	return c.language, c.fileType
+}
+
+// ClassifyName returns the language and file type of the file at path from
+// its name: a well-known file name first, then its extension
+func (r *Registry) ClassifyName(path string) (language, fileType string) {
+	if c, ok := r.names[filepath.Base(path)]; ok {
+		return c.language, c.fileType
+	}
+	return r.ClassifyExtension(filepath.Ext(path))
+}
+
+// ClassifyShebang returns the language of a script from its first line,
+// e.g. "#!/usr/bin/env python3", or "" when the interpreter is unknown
+func (r *Registry) ClassifyShebang(line string) string {
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip the options of env, e.g. "env -S deno run"
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = field
+				break
+			}
+		}
+	}
+	if language, ok := r.interpreters[interpreter]; ok {
+		return language
+	}
+	// Versioned interpreters, e.g. python3.12
+	return r.interpreters[strings.TrimRight(interpreter, "0123456789.")]
+}
+
+// Classify returns the language and file type of the file at path from its
+// name, reading the shebang line of files without an extension
+func (r *Registry) Classify(path string) (language, fileType string) {
+	if language, fileType = r.ClassifyName(path); language != "" || filepath.Ext(path) != "" {
+		return language, fileType
+	}
+	if language = r.ClassifyShebang(firstLine(path)); language != "" {
+		return language, "source"
+	}
+	return "", ""
+}
+
+// Matcher returns a Matcher collecting the files of the given extensions,
+// along with the well-known files and scripts of their languages, such as
+// go.mod for ".go". Other source extensions of those languages are not
+// collected unless listed, so ".ts" leaves out ".tsx" and ".c" leaves out
+// ".h". Listed extensions the registry does not know are collected as
+// sources of a language named after the extension. Without extensions it
+// collects every file the registry recognizes.
+func (r *Registry) Matcher(extensions []string) Matcher {
+	listed := make(map[string]bool)
+	languages := make(map[string]bool)
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		listed[ext] = true
+		if language, _ := r.ClassifyExtension(ext); language != "" {
+			languages[language] = true
+		}
+	}
+
+	return func(path string) (string, string, bool) {
+		language, fileType := r.Classify(path)
+		if len(listed) == 0 {
+			return language, fileType, language != ""
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		switch {
+		case listed[ext] && language == "":
+			return strings.TrimPrefix(ext, "."), "source", true
+		case listed[ext] || languages[language] && !r.sourceExtension(language, ext):
+			return language, fileType, true
+		}
+		return "", "", false
+	}
+}
+
+// sourceExtension reports whether ext is one of the Extensions of the
+// language called name, as opposed to its Files and Interpreters
+func (r *Registry) sourceExtension(name, ext string) bool {
+	for _, e := range r.languages[name].Extensions {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstLine returns the start of the first line of the file at path
+func firstLine(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	line, _ := bufio.NewReaderSize(f, 256).ReadSlice('\n')
+	return strings.TrimSpace(string(line))
+}
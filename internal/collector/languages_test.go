@@ -0,0 +1,91 @@
+// autodoc/internal/collector/languages_test.go
+
+package collector
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistryClassify(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":          "package main\n",
+		"go.mod":           "module example.com/app\n",
+		"App.CSPROJ":       "<Project />\n",
+		"web/package.json": "{}\n",
+		"web/index.mjs":    "export {}\n",
+		"Dockerfile":       "FROM scratch\n",
+		"Makefile":         "all:\n",
+		"bin/deploy":       "#!/usr/bin/env python3.12\nprint('hi')\n",
+		"bin/setup":        "#!/bin/bash -e\necho hi\n",
+		"bin/data":         "not a script\n",
+		"notes.mod":        "module-ish\n",
+	})
+
+	tests := []struct {
+		name     string
+		language string
+		fileType string
+	}{
+		{"main.go", "go", "source"},
+		{"go.mod", "go", "module"},
+		{"App.CSPROJ", "csharp", "project"},
+		{"web/package.json", "javascript", "project"},
+		{"web/index.mjs", "javascript", "source"},
+		{"Dockerfile", "docker", "build"},
+		{"Makefile", "make", "build"},
+		{"bin/deploy", "python", "source"},
+		{"bin/setup", "shell", "source"},
+		{"bin/data", "", ""},
+		{"notes.mod", "", ""},
+	}
+	for _, tt := range tests {
+		language, fileType := Languages.Classify(filepath.Join(dir, filepath.FromSlash(tt.name)))
+		if language != tt.language || fileType != tt.fileType {
+			t.Errorf("Expected %s to be %s/%s, got %s/%s", tt.name, tt.language, tt.fileType, language, fileType)
+		}
+	}
+
+	if title := Languages.Title("csharp"); title != "C#" {
+		t.Errorf("Expected title C#, got %s", title)
+	}
+}
+
+func TestRegistryMatcher(t *testing.T) {
+	match := Languages.Matcher([]string{".go", "proto", ".ts", ".c", ".py", " "})
+
+	tests := []struct {
+		path     string
+		language string
+		ok       bool
+	}{
+		{"/repo/main.go", "go", true},
+		{"/repo/go.mod", "go", true}, // Well-known file of a listed language
+		{"/repo/api/api.proto", "proto", true},
+		{"/repo/app.cs", "", false},
+		{"/repo/Dockerfile", "", false},
+		{"/repo/web/tsconfig.json", "typescript", true},
+		{"/repo/web/app.tsx", "", false}, // Sibling extensions are not collected unless listed
+		{"/repo/web/lib.mts", "", false},
+		{"/repo/src/util.c", "c", true},
+		{"/repo/src/util.h", "", false},
+	}
+	for _, tt := range tests {
+		language, _, ok := match(tt.path)
+		if ok != tt.ok || language != tt.language {
+			t.Errorf("Expected %s to match %v as %q, got %v as %q", tt.path, tt.ok, tt.language, ok, language)
+		}
+	}
+
+	// Scripts of a listed language are collected through their shebang
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"deploy": "#!/usr/bin/env python3\nprint('ok')\n"})
+	if language, _, ok := match(filepath.Join(dir, "deploy")); !ok || language != "python" {
+		t.Errorf("Expected the script to match as python, got %v as %q", ok, language)
+	}
+
+	if _, _, ok := Languages.Matcher(nil)("/repo/Dockerfile"); !ok {
+		t.Errorf("Expected every known language to match without extensions")
+	}
+}
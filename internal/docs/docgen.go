@@ -15,6 +15,7 @@ import (
 	"github.com/gomarkdown/markdown/html"
 	"github.com/gomarkdown/markdown/parser"
 	analyzer "github.com/rgehrsitz/AutoDoc/internal/analysis"
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
 )
 
 // DocumentationGenerator handles the generation of documentation
@@ -370,15 +371,8 @@ func (g *DocumentationGenerator) generateIndexPage(structure *analyzer.ProjectSt
 // Helper functions for GenerateDocumentation
 func determineLanguage(analyses map[string]string) string {
 	for path := range analyses {
-		switch {
-		case strings.HasSuffix(path, ".go"):
-			return "Go"
-		case strings.HasSuffix(path, ".cs"):
-			return "C#"
-		case strings.HasSuffix(path, ".js"):
-			return "JavaScript"
-		case strings.HasSuffix(path, ".py"):
-			return "Python"
+		if language, _ := collector.Languages.ClassifyName(path); language != "" {
+			return collector.Languages.Title(language)
 		}
 	}
 	return "unknown"
@@ -386,10 +380,10 @@ func determineLanguage(analyses map[string]string) string {
 
 func determineProjectType(analyses map[string]string) string {
 	for path := range analyses {
-		switch {
-		case strings.HasSuffix(path, "go.mod"):
+		switch language, fileType := collector.Languages.ClassifyName(path); {
+		case language == "go" && fileType == "module":
 			return "go-module"
-		case strings.HasSuffix(path, ".sln"):
+		case language == "csharp" && fileType == "solution":
 			return "dotnet-solution"
 		}
 	}
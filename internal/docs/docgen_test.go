@@ -0,0 +1,54 @@
+// autodoc/internal/docs/docgen_test.go
+
+package docs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	analyzer "github.com/rgehrsitz/AutoDoc/internal/analysis"
+	"github.com/rgehrsitz/AutoDoc/internal/testutil"
+)
+
+func TestGenerateReplay(t *testing.T) {
+	analysis := testutil.ReplayCalculator(t)
+
+	out := t.TempDir()
+	generator, err := NewDocumentationGenerator(Config{
+		OutputDir:    out,
+		ProjectName:  "calculator",
+		TemplatePath: filepath.Join("..", "..", "web", "handlers", "templates"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create documentation generator: %v", err)
+	}
+
+	structure := &analyzer.ProjectStructure{
+		Language: "Go",
+		Type:     "go-module",
+		Components: []analyzer.ProjectComponent{{
+			Name:        "calculator.go",
+			Path:        testutil.CalculatorPath,
+			Type:        "file",
+			Description: analyzer.FormatAnalysisMarkdown(analysis),
+		}},
+	}
+	if err := generator.Generate(structure); err != nil {
+		t.Fatalf("Failed to generate documentation: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "index.html")); err != nil {
+		t.Errorf("Expected an index page: %v", err)
+	}
+	page, err := os.ReadFile(filepath.Join(out, "components", "math", "calculator.html"))
+	if err != nil {
+		t.Fatalf("Failed to read component page: %v", err)
+	}
+	for _, want := range []string{analysis.Purpose, "Returns the sum of two integers."} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("Expected the component page to contain %q", want)
+		}
+	}
+}
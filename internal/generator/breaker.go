@@ -0,0 +1,86 @@
+// autodoc/internal/generator/breaker.go
+
+package generator
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
+
+// Default circuit breaker settings
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// CircuitBreaker pauses all LLM calls after repeated server errors, giving
+// an overloaded provider time to recover instead of hammering it from every
+// worker. After the cooldown calls resume; the next server error reopens the
+// breaker until a call succeeds.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	mu        sync.Mutex
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive
+// server errors and stays open for cooldown
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Wait blocks while the breaker is open or until the context is cancelled
+func (b *CircuitBreaker) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		wait := time.Until(b.openUntil)
+		b.mu.Unlock()
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Record updates the breaker with the outcome of a call
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	if llm.StatusCode(err) < 500 {
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold && !time.Now().Before(b.openUntil) {
+		b.openUntil = time.Now().Add(b.cooldown)
+		log.Printf("Circuit breaker open after %d server errors, pausing LLM calls for %s", b.failures, b.cooldown)
+	}
+}
+
+// Open reports whether calls are currently paused
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().Before(b.openUntil)
+}
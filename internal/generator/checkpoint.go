@@ -0,0 +1,253 @@
+// autodoc/internal/generator/checkpoint.go
+
+package generator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/storage"
+)
+
+// Checkpoint records the per-file progress of a run in a RunStore so an
+// interrupted run can be resumed. A nil Checkpoint records nothing and
+// processes every file.
+type Checkpoint struct {
+	store  storage.RunStore
+	run    *storage.Run
+	root   string
+	states map[string]*storage.FileState
+	mu     sync.Mutex
+}
+
+// NewRunID returns a new, sortable run identifier
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// StartRun records a new run for source. Files are tracked by their path
+// relative to root, so a resumed run matches them however root is spelled.
+func StartRun(store storage.RunStore, source, root string) (*Checkpoint, error) {
+	now := time.Now()
+	run := &storage.Run{
+		ID:        NewRunID(),
+		Source:    source,
+		Status:    storage.RunRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := store.SaveRun(run); err != nil {
+		return nil, fmt.Errorf("failed to start run: %w", err)
+	}
+
+	return &Checkpoint{
+		store:  store,
+		run:    run,
+		root:   root,
+		states: make(map[string]*storage.FileState),
+	}, nil
+}
+
+// ResumeRun loads an earlier run and its file states. Files that are done
+// are skipped; pending and failed files are processed again.
+func ResumeRun(store storage.RunStore, id, root string) (*Checkpoint, error) {
+	run, err := store.GetRun(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load run: %w", err)
+	}
+	if run == nil {
+		return nil, fmt.Errorf("run %s not found", id)
+	}
+
+	states, err := store.ListFileStates(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load run files: %w", err)
+	}
+
+	c := &Checkpoint{
+		store:  store,
+		run:    run,
+		root:   root,
+		states: make(map[string]*storage.FileState, len(states)),
+	}
+	for _, state := range states {
+		c.states[state.Path] = state
+	}
+
+	run.Status = storage.RunRunning
+	run.UpdatedAt = time.Now()
+	if err := store.SaveRun(run); err != nil {
+		return nil, fmt.Errorf("failed to resume run: %w", err)
+	}
+
+	return c, nil
+}
+
+// ID returns the run identifier
+func (c *Checkpoint) ID() string {
+	if c == nil {
+		return ""
+	}
+	return c.run.ID
+}
+
+// ShouldProcess reports whether path still needs processing in this run
+func (c *Checkpoint) ShouldProcess(path string) bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.states[c.key(path)]
+	return !ok || state.Status != storage.FileDone
+}
+
+// Queue records the given files as pending unless the run already knows them
+func (c *Checkpoint) Queue(paths ...string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var added []*storage.FileState
+	for _, path := range paths {
+		key := c.key(path)
+		if _, ok := c.states[key]; ok {
+			continue
+		}
+		state := &storage.FileState{
+			RunID:     c.run.ID,
+			Path:      key,
+			Status:    storage.FilePending,
+			UpdatedAt: time.Now(),
+		}
+		c.states[key] = state
+		added = append(added, state)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return c.store.SaveFileStates(added)
+}
+
+// Done records that path was processed successfully
+func (c *Checkpoint) Done(path string) error {
+	return c.update(path, storage.FileDone, nil)
+}
+
+// Failed records that processing path failed with err. When err reports the
+// failed stage or the raw analyzer response, they are recorded too.
+func (c *Checkpoint) Failed(path string, err error) error {
+	return c.update(path, storage.FileFailed, err)
+}
+
+// IsFailed reports whether the last attempt at path failed
+func (c *Checkpoint) IsFailed(path string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.states[c.key(path)]
+	return ok && state.Status == storage.FileFailed
+}
+
+// Failures returns the files whose last attempt failed, sorted by path
+func (c *Checkpoint) Failures() []storage.FileState {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var failures []storage.FileState
+	for _, state := range c.states {
+		if state.Status == storage.FileFailed {
+			failures = append(failures, *state)
+		}
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Path < failures[j].Path
+	})
+	return failures
+}
+
+// update saves the new state of path
+func (c *Checkpoint) update(path string, status storage.FileStatus, cause error) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := c.key(path)
+	state, ok := c.states[key]
+	if !ok {
+		state = &storage.FileState{RunID: c.run.ID, Path: key}
+		c.states[key] = state
+	}
+	state.Status = status
+	state.Attempts++
+	state.Error, state.Stage, state.RawResponse = "", "", ""
+	if cause != nil {
+		state.Error = cause.Error()
+		var staged interface{ FailedStage() string }
+		if errors.As(cause, &staged) {
+			state.Stage = staged.FailedStage()
+		}
+		var raw interface{ RawResponse() string }
+		if errors.As(cause, &raw) {
+			state.RawResponse = raw.RawResponse()
+		}
+	}
+	state.UpdatedAt = time.Now()
+
+	return c.store.SaveFileStates([]*storage.FileState{state})
+}
+
+// key identifies path by its slash-separated path relative to the root
+func (c *Checkpoint) key(path string) string {
+	if rel, err := filepath.Rel(c.root, path); err == nil && c.root != "" {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// Counts returns how many files of the run are in each state
+func (c *Checkpoint) Counts() map[storage.FileStatus]int {
+	counts := make(map[storage.FileStatus]int)
+	if c == nil {
+		return counts
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, state := range c.states {
+		counts[state.Status]++
+	}
+	return counts
+}
+
+// Finish records the final status of the run
+func (c *Checkpoint) Finish(status storage.RunStatus) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.run.Status = status
+	c.run.UpdatedAt = time.Now()
+	return c.store.SaveRun(c.run)
+}
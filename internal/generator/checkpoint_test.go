@@ -0,0 +1,86 @@
+// autodoc/internal/generator/checkpoint_test.go
+
+package generator
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/rgehrsitz/AutoDoc/internal/storage"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	store, err := storage.NewBadgerStorage(filepath.Join(dir, "db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	paths := []string{filepath.Join(src, "a.go"), filepath.Join(src, "b.go"), filepath.Join(src, "c.go")}
+	checkpoint, err := StartRun(store, src, src)
+	if err != nil {
+		t.Fatalf("Failed to start run: %v", err)
+	}
+	if err := checkpoint.Queue(paths...); err != nil {
+		t.Fatalf("Failed to queue files: %v", err)
+	}
+	if err := checkpoint.Done(paths[0]); err != nil {
+		t.Fatalf("Failed to mark file done: %v", err)
+	}
+	if err := checkpoint.Failed(paths[1], errors.New("model refused")); err != nil {
+		t.Fatalf("Failed to mark file failed: %v", err)
+	}
+	if err := checkpoint.Finish(storage.RunFailed); err != nil {
+		t.Fatalf("Failed to finish run: %v", err)
+	}
+
+	counts := checkpoint.Counts()
+	if counts[storage.FileDone] != 1 || counts[storage.FileFailed] != 1 || counts[storage.FilePending] != 1 {
+		t.Fatalf("Expected 1 done, 1 failed and 1 pending file, got %v", counts)
+	}
+
+	// Resume from a differently spelled root
+	resumed, err := ResumeRun(store, checkpoint.ID(), src+string(filepath.Separator))
+	if err != nil {
+		t.Fatalf("Failed to resume run: %v", err)
+	}
+	if resumed.ShouldProcess(paths[0]) {
+		t.Error("Expected the done file to be skipped")
+	}
+	if !resumed.ShouldProcess(paths[1]) || !resumed.ShouldProcess(paths[2]) {
+		t.Error("Expected the failed and pending files to be processed")
+	}
+	if !resumed.IsFailed(paths[1]) || resumed.IsFailed(paths[2]) {
+		t.Error("Expected only b.go to be failed")
+	}
+	failures := resumed.Failures()
+	if len(failures) != 1 || failures[0].Error != "model refused" {
+		t.Errorf("Expected the failure of b.go, got %+v", failures)
+	}
+
+	for _, path := range paths[1:] {
+		if err := resumed.Done(path); err != nil {
+			t.Fatalf("Failed to mark file done: %v", err)
+		}
+	}
+	if err := resumed.Finish(storage.RunCompleted); err != nil {
+		t.Fatalf("Failed to finish run: %v", err)
+	}
+	saved, err := store.GetRun(checkpoint.ID())
+	if err != nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	if saved.Status != storage.RunCompleted {
+		t.Errorf("Expected run status %s, got %s", storage.RunCompleted, saved.Status)
+	}
+	if counts := resumed.Counts(); counts[storage.FileDone] != 3 {
+		t.Errorf("Expected 3 done files, got %v", counts)
+	}
+
+	if _, err := ResumeRun(store, "missing", src); err == nil {
+		t.Error("Expected an error for an unknown run")
+	}
+}
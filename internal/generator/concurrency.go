@@ -0,0 +1,192 @@
+// autodoc/internal/generator/concurrency.go
+
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+const (
+	// throttleBackoff scales the limit down after a 429 response
+	throttleBackoff = 0.5
+	// latencyBackoff scales the limit down when latency rises
+	latencyBackoff = 0.75
+	// latencyTolerance is how far recent latency may rise above the
+	// long-run average before the limit is cut
+	latencyTolerance = 1.5
+	// latencySamples is how many successful calls are needed before
+	// latency is trusted
+	latencySamples = 5
+	// Weights of the recent and long-run latency averages
+	recentWeight  = 0.3
+	longRunWeight = 0.05
+)
+
+// AdaptiveLimiter bounds the number of LLM calls in flight and adjusts the
+// bound with AIMD: each successful call raises it by 1/limit, so it grows by
+// about one per round of calls, while a 429 response halves it and rising
+// latency cuts it by a quarter. Only calls started after the last cut can
+// cut it again, so one burst of throttling costs one cut. A nil
+// AdaptiveLimiter does not limit anything.
+type AdaptiveLimiter struct {
+	min, max int
+	limit    float64
+	inFlight int
+	recent   float64 // Average latency of recent calls, in seconds
+	longRun  float64 // Long-run average latency, in seconds
+	samples  int
+	lastCut  time.Time
+	stats    ConcurrencyStats
+	notify   chan struct{} // Closed when a slot may have become free
+	mu       sync.Mutex
+}
+
+// ConcurrencyStats summarizes the adjustments made by an AdaptiveLimiter
+type ConcurrencyStats struct {
+	Limit        int // Current limit
+	Min          int
+	Max          int
+	Peak         int // Highest limit reached
+	Throttled    int // Calls rejected with a 429
+	ThrottleCuts int // Cuts caused by throttling
+	LatencyCuts  int // Cuts caused by rising latency
+	Increases    int // Times the limit grew by one
+}
+
+// NewAdaptiveLimiterFromConfig creates the limiter described by the LLM settings
+func NewAdaptiveLimiterFromConfig(cfg config.LLMConfig) *AdaptiveLimiter {
+	return NewAdaptiveLimiter(cfg.MinConcurrency, cfg.MaxConcurrency)
+}
+
+// NewAdaptiveLimiter creates a limiter that starts at min calls in flight
+// and never goes below min or above max
+func NewAdaptiveLimiter(min, max int) *AdaptiveLimiter {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return &AdaptiveLimiter{
+		min:    min,
+		max:    max,
+		limit:  float64(min),
+		stats:  ConcurrencyStats{Limit: min, Min: min, Max: max, Peak: min},
+		notify: make(chan struct{}),
+	}
+}
+
+// Acquire blocks until a call may start or ctx is cancelled. It returns the
+// start time to pass to Release.
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) (time.Time, error) {
+	if l == nil {
+		return time.Now(), nil
+	}
+	for {
+		l.mu.Lock()
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			l.mu.Unlock()
+			return time.Now(), nil
+		}
+		notify := l.notify
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return time.Time{}, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+// Release ends a call started at started and adjusts the limit to its outcome
+func (l *AdaptiveLimiter) Release(started time.Time, err error) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	switch {
+	case llm.StatusCode(err) == http.StatusTooManyRequests:
+		l.stats.Throttled++
+		if started.After(l.lastCut) && l.limit > float64(l.min) {
+			l.cut(throttleBackoff)
+			l.stats.ThrottleCuts++
+			log.Printf("LLM calls throttled, reducing concurrency to %d", int(l.limit))
+		}
+	case err == nil:
+		rising := l.observe(time.Since(started))
+		switch {
+		case rising && started.After(l.lastCut) && l.limit > float64(l.min):
+			l.cut(latencyBackoff)
+			l.stats.LatencyCuts++
+			log.Printf("LLM latency rising, reducing concurrency to %d", int(l.limit))
+		case !rising && l.limit < float64(l.max):
+			before := int(l.limit)
+			l.limit = min(l.limit+1/l.limit, float64(l.max))
+			if int(l.limit) > before {
+				l.stats.Increases++
+			}
+		}
+	}
+
+	l.stats.Limit = int(l.limit)
+	l.stats.Peak = max(l.stats.Peak, l.stats.Limit)
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// observe adds a successful call's latency to the averages and reports
+// whether recent latency has risen beyond the tolerance
+func (l *AdaptiveLimiter) observe(latency time.Duration) bool {
+	seconds := latency.Seconds()
+	l.samples++
+	if l.samples == 1 {
+		l.recent, l.longRun = seconds, seconds
+		return false
+	}
+	l.recent += recentWeight * (seconds - l.recent)
+	l.longRun += longRunWeight * (seconds - l.longRun)
+	return l.samples >= latencySamples && l.recent > latencyTolerance*l.longRun
+}
+
+// cut scales the limit down by factor, keeping it at least min
+func (l *AdaptiveLimiter) cut(factor float64) {
+	l.limit = max(float64(l.min), float64(int(l.limit*factor)))
+	l.lastCut = time.Now()
+	// Latency measured at the old concurrency no longer applies
+	l.recent = l.longRun
+}
+
+// Stats returns the current limit and the adjustments made so far
+func (l *AdaptiveLimiter) Stats() ConcurrencyStats {
+	if l == nil {
+		return ConcurrencyStats{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// WriteSummary writes the concurrency reached and the throttling events
+func (l *AdaptiveLimiter) WriteSummary(w io.Writer) {
+	if l == nil {
+		return
+	}
+	stats := l.Stats()
+	fmt.Fprintf(w, "Concurrency: %d calls in flight (min %d, max %d, peak %d)\n", stats.Limit, stats.Min, stats.Max, stats.Peak)
+	fmt.Fprintf(w, "  Throttling: %d calls rejected with 429, %d cuts on throttling, %d cuts on rising latency\n",
+		stats.Throttled, stats.ThrottleCuts, stats.LatencyCuts)
+}
@@ -0,0 +1,105 @@
+// autodoc/internal/generator/concurrency_test.go
+
+package generator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
+
+// call acquires a slot from l and releases it as if the call took latency
+func call(t *testing.T, l *AdaptiveLimiter, latency time.Duration, err error) {
+	t.Helper()
+	if _, acqErr := l.Acquire(context.Background()); acqErr != nil {
+		t.Fatalf("Failed to acquire a slot: %v", acqErr)
+	}
+	l.Release(time.Now().Add(-latency), err)
+}
+
+func TestAdaptiveLimiterAIMD(t *testing.T) {
+	l := NewAdaptiveLimiter(1, 4)
+
+	// Successful calls raise the limit by one per round, up to max
+	for i := 0; i < 20; i++ {
+		call(t, l, 100*time.Millisecond, nil)
+	}
+	if stats := l.Stats(); stats.Limit != 4 || stats.Increases != 3 {
+		t.Errorf("Expected the limit to grow to 4 in 3 steps, got %+v", stats)
+	}
+
+	// A burst of 429s from calls started before the cut costs one cut
+	throttled := &llm.APIError{StatusCode: 429}
+	started := time.Now().Add(-time.Second)
+	for i := 0; i < 3; i++ {
+		if _, err := l.Acquire(context.Background()); err != nil {
+			t.Fatalf("Failed to acquire a slot: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		l.Release(started, throttled)
+	}
+	stats := l.Stats()
+	if stats.Limit != 2 || stats.Throttled != 3 || stats.ThrottleCuts != 1 {
+		t.Errorf("Expected one cut to 2 after 3 throttled calls, got %+v", stats)
+	}
+	if stats.Peak != 4 {
+		t.Errorf("Expected peak 4, got %d", stats.Peak)
+	}
+
+	// The limit never drops below min
+	for i := 0; i < 5; i++ {
+		time.Sleep(time.Millisecond)
+		call(t, l, 0, throttled)
+	}
+	if limit := l.Stats().Limit; limit != 1 {
+		t.Errorf("Expected the limit to stay at min 1, got %d", limit)
+	}
+}
+
+func TestAdaptiveLimiterRisingLatency(t *testing.T) {
+	l := NewAdaptiveLimiter(1, 8)
+	for i := 0; i < 30; i++ {
+		call(t, l, 100*time.Millisecond, nil)
+	}
+	before := l.Stats().Limit
+
+	for i := 0; i < 5 && l.Stats().LatencyCuts == 0; i++ {
+		call(t, l, time.Second, nil)
+	}
+	stats := l.Stats()
+	if stats.LatencyCuts != 1 || stats.Limit >= before {
+		t.Errorf("Expected rising latency to cut the limit below %d, got %+v", before, stats)
+	}
+}
+
+func TestAdaptiveLimiterAcquireBlocks(t *testing.T) {
+	l := NewAdaptiveLimiter(1, 1)
+	started, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire a slot: %v", err)
+	}
+
+	// The second call waits for the first one
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the second call to wait until the deadline, got %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if _, err := l.Acquire(context.Background()); err == nil {
+			close(acquired)
+		}
+	}()
+	l.Release(started, nil)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Error("Expected a released slot to be handed to the waiting call")
+	}
+}
@@ -0,0 +1,99 @@
+// autodoc/internal/generator/limitstate.go
+
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// lockTimeout bounds how long a process waits for the state lock
+	lockTimeout = 10 * time.Second
+	// staleLockAge is how old a lock file must be before it is assumed to
+	// belong to a crashed process and removed
+	staleLockAge = 30 * time.Second
+	// lockRetryDelay is the pause between attempts to take the lock; the lock
+	// is only held while the state file is read and written
+	lockRetryDelay = 5 * time.Millisecond
+)
+
+// fileStore keeps limiter state in a JSON file shared between processes. A
+// lock file created with O_EXCL serializes access on every platform.
+type fileStore struct {
+	path string
+}
+
+func (f *fileStore) update(fn func(state *limiterState)) error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var state limiterState
+	data, err := os.ReadFile(f.path)
+	switch {
+	case err == nil:
+		// A corrupt file is treated like a missing one and rewritten
+		if json.Unmarshal(data, &state) != nil {
+			state = limiterState{}
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to read limiter state: %w", err)
+	}
+
+	fn(&state)
+
+	data, err = json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal limiter state: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".ratelimit-*")
+	if err != nil {
+		return fmt.Errorf("failed to create limiter state: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write limiter state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write limiter state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save limiter state: %w", err)
+	}
+	return nil
+}
+
+// lock takes the lock file next to the state file and returns its release function
+func (f *fileStore) lock() (func(), error) {
+	lockPath := f.path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			lockFile.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock file %s", lockPath)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
@@ -4,57 +4,232 @@ package generator
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"math"
 	"math/rand/v2"
 	"sync"
 	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
 )
 
-// RateLimiter implements a token bucket rate limiter
+// RateLimiter limits LLM calls with two token buckets: one for requests and
+// one for model tokens. Callers reserve an estimate of the tokens a call will
+// use and reconcile it with the actual usage afterwards. Waiters sleep until
+// the buckets are due to refill, or until a reconciliation returns tokens,
+// instead of polling.
 type RateLimiter struct {
-	tokens         float64
-	maxTokens      float64
-	refillRate     float64
-	lastRefillTime time.Time
-	mu             sync.Mutex
+	requests bucketConfig
+	tokens   bucketConfig
+	store    limiterStore
+	mu       sync.Mutex
+	wake     chan struct{} // Closed and replaced whenever capacity is returned
+}
+
+// bucketConfig describes one token bucket. A zero capacity means unlimited.
+type bucketConfig struct {
+	capacity float64
+	rate     float64 // Refill per second
+}
+
+// limiterState holds the current level of both buckets
+type limiterState struct {
+	Requests float64   `json:"requests"`
+	Tokens   float64   `json:"tokens"`
+	Updated  time.Time `json:"updated"`
+}
+
+// limiterStore reads and writes limiter state under a lock
+type limiterStore interface {
+	update(fn func(state *limiterState)) error
+}
+
+// memoryStore keeps limiter state in process
+type memoryStore struct {
+	mu    sync.Mutex
+	state limiterState
+}
+
+func (m *memoryStore) update(fn func(state *limiterState)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(&m.state)
+	return nil
 }
 
-// NewRateLimiter creates a new rate limiter with the given parameters
+// NewRateLimiter creates a request-only rate limiter that allows bursts of
+// maxTokens requests and refills refillRate requests per second
 func NewRateLimiter(maxTokens float64, refillRate float64) *RateLimiter {
 	return &RateLimiter{
-		tokens:         maxTokens,
-		maxTokens:      maxTokens,
-		refillRate:     refillRate,
-		lastRefillTime: time.Now(),
+		requests: bucketConfig{capacity: maxTokens, rate: refillRate},
+		store:    &memoryStore{},
+		wake:     make(chan struct{}),
+	}
+}
+
+// NewDualRateLimiter creates a limiter enforcing both requests per minute and
+// tokens per minute. A zero limit disables that bucket.
+func NewDualRateLimiter(requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	return &RateLimiter{
+		requests: bucketConfig{capacity: float64(requestsPerMinute), rate: float64(requestsPerMinute) / 60},
+		tokens:   bucketConfig{capacity: float64(tokensPerMinute), rate: float64(tokensPerMinute) / 60},
+		store:    &memoryStore{},
+		wake:     make(chan struct{}),
 	}
 }
 
-// Wait blocks until a token is available or the context is cancelled
+// NewRateLimiterFromConfig creates the limiter described by the LLM settings
+func NewRateLimiterFromConfig(cfg config.LLMConfig) *RateLimiter {
+	limiter := NewDualRateLimiter(cfg.RequestsPerMinute, cfg.TokensPerMinute)
+	if cfg.RateLimitFile != "" {
+		limiter.SetStateFile(cfg.RateLimitFile)
+	}
+	return limiter
+}
+
+// SetStateFile shares the limiter state with other processes through a file
+// guarded by a lock file, so parallel jobs stay within one quota together.
+// It must be called before the limiter is used.
+func (r *RateLimiter) SetStateFile(path string) {
+	r.store = &fileStore{path: path}
+}
+
+// Reservation is capacity taken from a RateLimiter for one call
+type Reservation struct {
+	limiter *RateLimiter
+	tokens  float64
+}
+
+// Wait blocks until a request is allowed or the context is cancelled
 func (r *RateLimiter) Wait(ctx context.Context) error {
+	_, err := r.Reserve(ctx, 0)
+	return err
+}
+
+// Reserve blocks until a request and the estimated number of tokens are
+// available, then takes them. Estimates larger than the token bucket are
+// capped to its capacity so they can still proceed once it is full.
+func (r *RateLimiter) Reserve(ctx context.Context, tokens int) (*Reservation, error) {
+	need := float64(tokens)
+	if r.tokens.capacity == 0 {
+		need = 0
+	} else if need > r.tokens.capacity {
+		need = r.tokens.capacity
+	}
+
 	for {
+		var wait time.Duration
+		err := r.store.update(func(state *limiterState) {
+			r.refill(state, time.Now())
+			wait = r.take(state, need)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update rate limiter: %w", err)
+		}
+		if wait == 0 {
+			return &Reservation{limiter: r, tokens: need}, nil
+		}
+
+		r.mu.Lock()
+		wake := r.wake
+		r.mu.Unlock()
+
+		timer := time.NewTimer(wait)
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			r.mu.Lock()
-			now := time.Now()
-			timePassed := now.Sub(r.lastRefillTime).Seconds()
-			r.tokens = math.Min(r.maxTokens, r.tokens+timePassed*r.refillRate)
-			r.lastRefillTime = now
-
-			if r.tokens >= 1 {
-				r.tokens--
-				r.mu.Unlock()
-				return nil
-			}
-			r.mu.Unlock()
-
-			// Sleep for a short duration before trying again
-			time.Sleep(100 * time.Millisecond)
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
 		}
 	}
 }
 
+// Reconcile replaces the reserved token estimate with the tokens actually
+// used. Unused tokens are returned to the bucket and waiters are woken; extra
+// tokens are taken from it, delaying later calls.
+func (res *Reservation) Reconcile(actual int) {
+	r := res.limiter
+	if r.tokens.capacity == 0 {
+		return
+	}
+
+	delta := res.tokens - float64(actual)
+	res.tokens = float64(actual)
+	if delta == 0 {
+		return
+	}
+	if err := r.store.update(func(state *limiterState) {
+		r.refill(state, time.Now())
+		state.Tokens = math.Min(r.tokens.capacity, state.Tokens+delta)
+	}); err != nil {
+		log.Printf("Warning: failed to reconcile rate limiter usage: %v", err)
+		return
+	}
+
+	if delta > 0 {
+		r.mu.Lock()
+		close(r.wake)
+		r.wake = make(chan struct{})
+		r.mu.Unlock()
+	}
+}
+
+// refill adds the capacity accrued since the state was last updated. A
+// state that was never written starts with full buckets.
+func (r *RateLimiter) refill(state *limiterState, now time.Time) {
+	if state.Updated.IsZero() {
+		state.Requests = r.requests.capacity
+		state.Tokens = r.tokens.capacity
+		state.Updated = now
+		return
+	}
+	elapsed := now.Sub(state.Updated).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	state.Requests = math.Min(r.requests.capacity, state.Requests+elapsed*r.requests.rate)
+	state.Tokens = math.Min(r.tokens.capacity, state.Tokens+elapsed*r.tokens.rate)
+	state.Updated = now
+}
+
+// take removes one request and need tokens from the buckets and returns 0,
+// or returns how long to wait until both are available
+func (r *RateLimiter) take(state *limiterState, need float64) time.Duration {
+	var wait time.Duration
+	if r.requests.capacity > 0 && state.Requests < 1 {
+		wait = max(wait, untilAvailable(1-state.Requests, r.requests.rate))
+	}
+	if r.tokens.capacity > 0 && state.Tokens < need {
+		wait = max(wait, untilAvailable(need-state.Tokens, r.tokens.rate))
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	if r.requests.capacity > 0 {
+		state.Requests--
+	}
+	state.Tokens -= need
+	return 0
+}
+
+// untilAvailable returns how long a bucket refilling at rate per second takes
+// to gain missing units
+func untilAvailable(missing, rate float64) time.Duration {
+	if rate <= 0 {
+		return time.Second
+	}
+	wait := time.Duration(missing / rate * float64(time.Second))
+	if wait < time.Millisecond {
+		wait = time.Millisecond
+	}
+	return wait
+}
+
 // RetryConfig defines the configuration for retry behavior
 type RetryConfig struct {
 	MaxRetries int
@@ -73,18 +248,30 @@ func DefaultRetryConfig() RetryConfig {
 	}
 }
 
-// WithRetry wraps a function with retry logic using exponential backoff
+// WithRetry wraps a function with retry logic using exponential backoff.
+// Only errors classified as retryable are retried, and a provider's
+// Retry-After is used when it is longer than the backoff.
 func WithRetry[T any](ctx context.Context, cfg RetryConfig, fn func(context.Context) (T, error)) (T, error) {
 	var lastErr error
 	var result T
 
 	for attempt := 0; attempt <= cfg.MaxRetries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
 		result, lastErr = fn(ctx)
 		if lastErr == nil {
 			return result, nil
 		}
 
-		if attempt == cfg.MaxRetries {
+		// Failures caused by cancellation report the cancellation
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+
+		if attempt == cfg.MaxRetries || llm.Classify(lastErr) != llm.ErrorRetryable {
 			break
 		}
 
@@ -96,6 +283,9 @@ func WithRetry[T any](ctx context.Context, cfg RetryConfig, fn func(context.Cont
 		// Add jitter (±20%)
 		jitter := (rand.Float64()*0.4 - 0.2) * delay
 		delay += jitter
+		if retryAfter := llm.RetryAfterOf(lastErr); float64(retryAfter) > delay {
+			delay = float64(retryAfter)
+		}
 
 		timer := time.NewTimer(time.Duration(delay))
 		select {
@@ -0,0 +1,166 @@
+// autodoc/internal/generator/ratelimit_test.go
+
+package generator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
+
+func TestWithRetryClassification(t *testing.T) {
+	cfg := RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1}
+
+	// Fatal errors are returned without retrying
+	calls := 0
+	_, err := WithRetry(context.Background(), cfg, func(ctx context.Context) (int, error) {
+		calls++
+		return 0, &llm.APIError{StatusCode: 401, Code: "invalid_api_key"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected a single call for a fatal error, got %d calls (%v)", calls, err)
+	}
+
+	// Too-large errors are left to the caller
+	calls = 0
+	_, err = WithRetry(context.Background(), cfg, func(ctx context.Context) (int, error) {
+		calls++
+		return 0, &llm.APIError{StatusCode: 400, Code: "context_length_exceeded"}
+	})
+	if !errors.Is(err, llm.ErrTooLarge) || calls != 1 {
+		t.Errorf("Expected a single call for a too-large error, got %d calls (%v)", calls, err)
+	}
+
+	// Rate limits are retried after the provider's Retry-After
+	calls = 0
+	start := time.Now()
+	result, err := WithRetry(context.Background(), cfg, func(ctx context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, &llm.APIError{StatusCode: 429, RetryAfter: 50 * time.Millisecond}
+		}
+		return 42, nil
+	})
+	if err != nil || result != 42 {
+		t.Fatalf("Expected retry to succeed, got %d (%v)", result, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected to wait for Retry-After, waited %s", elapsed)
+	}
+
+	// A cancelled context stops before the next attempt
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	_, err = WithRetry(ctx, cfg, func(ctx context.Context) (int, error) {
+		calls++
+		cancel()
+		return 0, errors.New("temporary failure")
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("Expected cancellation after a single call, got %d calls (%v)", calls, err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := NewCircuitBreaker(2, 50*time.Millisecond)
+
+	// Client errors do not count towards opening the breaker
+	breaker.Record(&llm.APIError{StatusCode: 400})
+	breaker.Record(&llm.APIError{StatusCode: 503})
+	if breaker.Open() {
+		t.Fatal("Expected breaker to stay closed after one server error")
+	}
+
+	breaker.Record(&llm.APIError{StatusCode: 500})
+	if !breaker.Open() {
+		t.Fatal("Expected breaker to open after two server errors")
+	}
+
+	start := time.Now()
+	if err := breaker.Wait(context.Background()); err != nil {
+		t.Fatalf("Failed to wait for breaker: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected Wait to pause while open, returned after %s", elapsed)
+	}
+
+	// A cancelled context stops waiting
+	breaker.Record(&llm.APIError{StatusCode: 502})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := breaker.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	breaker.Record(nil)
+	breaker.Record(&llm.APIError{StatusCode: 500})
+	time.Sleep(60 * time.Millisecond)
+	if breaker.Open() {
+		t.Error("Expected a success to reset the failure count")
+	}
+}
+
+func TestRateLimiterReconcileWakesWaiters(t *testing.T) {
+	// 600 tokens per minute refill at 10 per second
+	limiter := NewDualRateLimiter(0, 600)
+
+	first, err := limiter.Reserve(context.Background(), 500)
+	if err != nil {
+		t.Fatalf("Failed to reserve tokens: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := limiter.Reserve(context.Background(), 400)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Expected the second reservation to wait, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The first call used far fewer tokens than estimated
+	first.Reconcile(100)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Failed to reserve tokens: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the returned tokens to wake the waiter")
+	}
+}
+
+func TestRateLimiterSharedStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+
+	// Two limiters stand in for two processes sharing a quota of 2 requests
+	a := NewDualRateLimiter(2, 0)
+	a.SetStateFile(path)
+	b := NewDualRateLimiter(2, 0)
+	b.SetStateFile(path)
+
+	for i := 0; i < 2; i++ {
+		if err := a.Wait(context.Background()); err != nil {
+			t.Fatalf("Failed to take request %d: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the shared quota to be exhausted, got %v", err)
+	}
+
+	if _, err := os.Stat(path + ".lock"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the lock file to be released, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+// autodoc/internal/generator/throttle.go
+
+package generator
+
+import (
+	"context"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
+
+// ThrottledProvider sends every call through a shared RateLimiter and
+// CircuitBreaker and retries failed calls with exponential backoff. With an
+// AdaptiveLimiter, every attempt also waits for a concurrency slot.
+type ThrottledProvider struct {
+	llm.Provider
+	limiter     *RateLimiter
+	breaker     *CircuitBreaker
+	concurrency *AdaptiveLimiter
+	retry       RetryConfig
+}
+
+// NewThrottledProvider wraps inner so that all callers share limiter
+func NewThrottledProvider(inner llm.Provider, limiter *RateLimiter, retry RetryConfig) *ThrottledProvider {
+	return &ThrottledProvider{
+		Provider: inner,
+		limiter:  limiter,
+		breaker:  NewCircuitBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown),
+		retry:    retry,
+	}
+}
+
+// SetConcurrency bounds the calls in flight with an adaptive limiter
+func (p *ThrottledProvider) SetConcurrency(limiter *AdaptiveLimiter) {
+	p.concurrency = limiter
+}
+
+// defaultCompletionEstimate is the number of response tokens reserved for
+// chat requests without a MaxTokens limit
+const defaultCompletionEstimate = 1024
+
+// reserve blocks until the breaker is closed, a concurrency slot is free and
+// the limiter grants a request with the estimated number of tokens. It
+// returns the start time of the call for settle.
+func (p *ThrottledProvider) reserve(ctx context.Context, tokens int) (*Reservation, time.Time, error) {
+	if err := p.breaker.Wait(ctx); err != nil {
+		return nil, time.Time{}, err
+	}
+	started, err := p.concurrency.Acquire(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	res, err := p.limiter.Reserve(ctx, tokens)
+	if err != nil {
+		p.concurrency.Release(started, err)
+		return nil, time.Time{}, err
+	}
+	return res, time.Now(), nil
+}
+
+// settle records the outcome of a call started at started with the breaker
+// and the limiters. Failed calls return their reservation; providers that
+// report no usage keep the estimate.
+func (p *ThrottledProvider) settle(res *Reservation, started time.Time, usage llm.Usage, err error) {
+	p.breaker.Record(err)
+	p.concurrency.Release(started, err)
+	switch {
+	case err != nil:
+		res.Reconcile(0)
+	case usage.TotalTokens > 0:
+		res.Reconcile(int(usage.TotalTokens))
+	}
+}
+
+// Chat performs a rate-limited chat completion with retries
+func (p *ThrottledProvider) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	completion := defaultCompletionEstimate
+	if req.MaxTokens > 0 {
+		completion = int(req.MaxTokens)
+	}
+	estimate := llm.EstimateRequestTokens(req.Messages) + completion
+
+	return WithRetry(ctx, p.retry, func(ctx context.Context) (*llm.ChatResponse, error) {
+		res, started, err := p.reserve(ctx, estimate)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := p.Provider.Chat(ctx, req)
+		var usage llm.Usage
+		if resp != nil {
+			usage = resp.Usage
+		}
+		p.settle(res, started, usage, err)
+		return resp, err
+	})
+}
+
+// Embed returns rate-limited embeddings with retries
+func (p *ThrottledProvider) Embed(ctx context.Context, inputs []string) (*llm.EmbeddingResponse, error) {
+	estimate := 0
+	for _, input := range inputs {
+		estimate += llm.EstimateTokens(input)
+	}
+
+	return WithRetry(ctx, p.retry, func(ctx context.Context) (*llm.EmbeddingResponse, error) {
+		res, started, err := p.reserve(ctx, estimate)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := p.Provider.Embed(ctx, inputs)
+		var usage llm.Usage
+		if resp != nil {
+			usage = resp.Usage
+		}
+		p.settle(res, started, usage, err)
+		return resp, err
+	})
+}
@@ -0,0 +1,208 @@
+// autodoc/internal/generator/throttle_test.go
+
+package generator
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+)
+
+// throttledProvider answers its first calls with a 429 and then succeeds,
+// running during inside every call
+type throttledProvider struct {
+	llm.Provider
+	throttled int
+	calls     int
+	during    func()
+}
+
+func (p *throttledProvider) call() error {
+	p.calls++
+	if p.during != nil {
+		p.during()
+	}
+	if p.calls <= p.throttled {
+		return &llm.APIError{StatusCode: 429, Code: "rate_limit_exceeded"}
+	}
+	return nil
+}
+
+func (p *throttledProvider) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	if err := p.call(); err != nil {
+		return nil, err
+	}
+	return &llm.ChatResponse{Content: "ok", Usage: llm.Usage{TotalTokens: 30}}, nil
+}
+
+func (p *throttledProvider) Embed(ctx context.Context, inputs []string) (*llm.EmbeddingResponse, error) {
+	if err := p.call(); err != nil {
+		return nil, err
+	}
+	return &llm.EmbeddingResponse{Embeddings: make([][]float64, len(inputs)), Usage: llm.Usage{TotalTokens: 30}}, nil
+}
+
+// tokensLeft returns the tokens currently available in limiter
+func tokensLeft(limiter *RateLimiter) float64 {
+	var left float64
+	limiter.store.update(func(state *limiterState) {
+		limiter.refill(state, time.Now())
+		left = state.Tokens
+	})
+	return left
+}
+
+// inFlight returns the calls currently holding a slot of limiter
+func inFlight(limiter *AdaptiveLimiter) int {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	return limiter.inFlight
+}
+
+// near reports whether got is within a few tokens of want, allowing for the
+// bucket refilling while the test runs
+func near(got, want float64) bool {
+	return math.Abs(got-want) < 5
+}
+
+func TestThrottledProviderRetriesThrottledCalls(t *testing.T) {
+	req := llm.ChatRequest{Messages: []llm.Message{llm.UserMessage("Describe the calculator")}, MaxTokens: 100}
+	inputs := []string{"first input", "second input"}
+
+	tests := []struct {
+		name     string
+		estimate int
+		call     func(p *ThrottledProvider) error
+	}{
+		{
+			name:     "chat",
+			estimate: llm.EstimateRequestTokens(req.Messages) + 100,
+			call: func(p *ThrottledProvider) error {
+				_, err := p.Chat(context.Background(), req)
+				return err
+			},
+		},
+		{
+			name:     "embed",
+			estimate: llm.EstimateTokens(inputs[0]) + llm.EstimateTokens(inputs[1]),
+			call: func(p *ThrottledProvider) error {
+				_, err := p.Embed(context.Background(), inputs)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 6000 tokens per minute refill at 100 per second
+			limiter := NewDualRateLimiter(0, 6000)
+			concurrency := NewAdaptiveLimiter(2, 4)
+			fake := &throttledProvider{throttled: 1}
+			p := NewThrottledProvider(fake, limiter, RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1})
+			p.SetConcurrency(concurrency)
+
+			// Every attempt holds one slot and one reservation; the 429
+			// returns its reservation before the retry takes a new one
+			fake.during = func() {
+				if n := inFlight(concurrency); n != 1 {
+					t.Errorf("Expected 1 call in flight during attempt %d, got %d", fake.calls, n)
+				}
+				if left := tokensLeft(limiter); !near(left, float64(6000-tt.estimate)) {
+					t.Errorf("Expected %d tokens left during attempt %d, got %.0f", 6000-tt.estimate, fake.calls, left)
+				}
+			}
+
+			if err := tt.call(p); err != nil {
+				t.Fatalf("Failed to call the provider: %v", err)
+			}
+			if fake.calls != 2 {
+				t.Errorf("Expected 2 attempts, got %d", fake.calls)
+			}
+
+			// The successful attempt is reconciled with its reported usage
+			if left := tokensLeft(limiter); !near(left, 6000-30) {
+				t.Errorf("Expected %d tokens left after the call, got %.0f", 6000-30, left)
+			}
+			if n := inFlight(concurrency); n != 0 {
+				t.Errorf("Expected no calls in flight after the call, got %d", n)
+			}
+			if stats := concurrency.Stats(); stats.Throttled != 1 {
+				t.Errorf("Expected 1 throttled call, got %d", stats.Throttled)
+			}
+			if p.breaker.Open() {
+				t.Error("Expected a 429 to leave the breaker closed")
+			}
+		})
+	}
+}
+
+func TestThrottledProviderWaitOrder(t *testing.T) {
+	req := llm.ChatRequest{Messages: []llm.Message{llm.UserMessage("Describe the calculator")}}
+	retry := RetryConfig{MaxRetries: 0, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1}
+
+	chat := func(p *ThrottledProvider) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := p.Chat(ctx, req)
+		return err
+	}
+
+	// An open breaker is waited for before taking a slot or tokens
+	limiter := NewDualRateLimiter(0, 6000)
+	concurrency := NewAdaptiveLimiter(1, 1)
+	fake := &throttledProvider{}
+	p := NewThrottledProvider(fake, limiter, retry)
+	p.SetConcurrency(concurrency)
+	p.breaker = NewCircuitBreaker(1, time.Minute)
+	p.breaker.Record(&llm.APIError{StatusCode: 500})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := chat(p); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the open breaker to time out, got %v", err)
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if n := inFlight(concurrency); n != 0 {
+		t.Errorf("Expected no slot taken while the breaker is open, got %d", n)
+	}
+	if left := tokensLeft(limiter); !near(left, 6000) {
+		t.Errorf("Expected no tokens reserved while the breaker is open, got %.0f left", left)
+	}
+	<-done
+
+	// A full concurrency limit is waited for before reserving tokens
+	limiter = NewDualRateLimiter(0, 6000)
+	p = NewThrottledProvider(fake, limiter, retry)
+	p.SetConcurrency(concurrency)
+	started, err := concurrency.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire a slot: %v", err)
+	}
+	if err := chat(p); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the full limit to time out, got %v", err)
+	}
+	if left := tokensLeft(limiter); !near(left, 6000) {
+		t.Errorf("Expected no tokens reserved while waiting for a slot, got %.0f left", left)
+	}
+	concurrency.Release(started, nil)
+
+	// A slot taken while waiting for tokens is given back when the wait fails
+	if _, err := limiter.Reserve(context.Background(), 6000); err != nil {
+		t.Fatalf("Failed to reserve tokens: %v", err)
+	}
+	if err := chat(p); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the empty bucket to time out, got %v", err)
+	}
+	if n := inFlight(concurrency); n != 0 {
+		t.Errorf("Expected the slot to be released, got %d in flight", n)
+	}
+
+	if fake.calls != 0 {
+		t.Errorf("Expected no provider calls, got %d", fake.calls)
+	}
+}
@@ -0,0 +1,258 @@
+// autodoc/internal/llm/accounting.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+// ErrBudgetExceeded is returned once a run has used up its token or cost budget
+var ErrBudgetExceeded = errors.New("LLM budget exceeded")
+
+// PriceTable maps model name prefixes to prices in US dollars per million
+// tokens. The longest matching prefix wins.
+type PriceTable map[string]config.ModelPrice
+
+// DefaultPriceTable returns list prices for common OpenAI models
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		"gpt-4o-mini":            {Prompt: 0.15, Completion: 0.60},
+		"gpt-4o":                 {Prompt: 2.50, Completion: 10.00},
+		"chatgpt-4o-latest":      {Prompt: 5.00, Completion: 15.00},
+		"gpt-4-turbo":            {Prompt: 10.00, Completion: 30.00},
+		"gpt-4":                  {Prompt: 30.00, Completion: 60.00},
+		"gpt-3.5-turbo":          {Prompt: 0.50, Completion: 1.50},
+		"text-embedding-3-small": {Prompt: 0.02},
+		"text-embedding-3-large": {Prompt: 0.13},
+		"text-embedding-ada-002": {Prompt: 0.10},
+	}
+}
+
+// NewPriceTable returns the default price table with the configured overrides applied
+func NewPriceTable(overrides map[string]config.ModelPrice) PriceTable {
+	table := DefaultPriceTable()
+	for model, price := range overrides {
+		table[model] = price
+	}
+	return table
+}
+
+// Lookup returns the price for a model
+func (t PriceTable) Lookup(model string) (config.ModelPrice, bool) {
+	model = strings.ToLower(model)
+	best := ""
+	for prefix := range t {
+		if strings.HasPrefix(model, strings.ToLower(prefix)) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return config.ModelPrice{}, false
+	}
+	return t[best], true
+}
+
+// Cost returns the cost of usage on a model in US dollars
+func (t PriceTable) Cost(model string, u Usage) float64 {
+	price, _ := t.Lookup(model)
+	return (float64(u.PromptTokens)*price.Prompt + float64(u.CompletionTokens)*price.Completion) / 1_000_000
+}
+
+// fileKey is the context key carrying the file an LLM call is made for
+type fileKey struct{}
+
+// WithFile labels LLM calls made with ctx as belonging to a file, so their
+// usage is attributed to it
+func WithFile(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, fileKey{}, path)
+}
+
+// FileFromContext returns the file label set by WithFile, if any
+func FileFromContext(ctx context.Context) string {
+	path, _ := ctx.Value(fileKey{}).(string)
+	return path
+}
+
+// FileUsage is the usage and cost attributed to one file
+type FileUsage struct {
+	Path  string  `json:"path"`
+	Usage Usage   `json:"usage"`
+	Cost  float64 `json:"cost"`
+	Calls int     `json:"calls"`
+}
+
+// Ledger records token usage and cost per file and per run, and enforces
+// optional token and cost budgets. It is safe for concurrent use.
+type Ledger struct {
+	mu        sync.Mutex
+	prices    PriceTable
+	maxTokens int64
+	maxCost   float64
+	total     Usage
+	cost      float64
+	calls     int
+	files     map[string]*FileUsage
+	unpriced  map[string]bool
+}
+
+// NewLedger creates a ledger. A zero maxTokens or maxCost disables that budget.
+func NewLedger(prices PriceTable, maxTokens int64, maxCost float64) *Ledger {
+	if prices == nil {
+		prices = DefaultPriceTable()
+	}
+	return &Ledger{
+		prices:    prices,
+		maxTokens: maxTokens,
+		maxCost:   maxCost,
+		files:     make(map[string]*FileUsage),
+		unpriced:  make(map[string]bool),
+	}
+}
+
+// Record adds the usage of one call on model to the file's and the run's totals
+func (l *Ledger) Record(file, model string, u Usage) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.prices.Lookup(model); !ok && model != "" {
+		l.unpriced[model] = true
+	}
+	cost := l.prices.Cost(model, u)
+
+	l.total = l.total.Add(u)
+	l.cost += cost
+	l.calls++
+
+	if file == "" {
+		file = "(unattributed)"
+	}
+	entry, ok := l.files[file]
+	if !ok {
+		entry = &FileUsage{Path: file}
+		l.files[file] = entry
+	}
+	entry.Usage = entry.Usage.Add(u)
+	entry.Cost += cost
+	entry.Calls++
+}
+
+// Check returns ErrBudgetExceeded once the token or cost budget has been reached
+func (l *Ledger) Check() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxTokens > 0 && l.total.TotalTokens >= l.maxTokens {
+		return fmt.Errorf("%w: used %d of %d tokens", ErrBudgetExceeded, l.total.TotalTokens, l.maxTokens)
+	}
+	if l.maxCost > 0 && l.cost >= l.maxCost {
+		return fmt.Errorf("%w: spent $%.4f of $%.4f", ErrBudgetExceeded, l.cost, l.maxCost)
+	}
+	return nil
+}
+
+// Total returns the run's usage and cost
+func (l *Ledger) Total() (Usage, float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total, l.cost
+}
+
+// Files returns the per-file usage, most expensive first
+func (l *Ledger) Files() []FileUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files := make([]FileUsage, 0, len(l.files))
+	for _, entry := range l.files {
+		files = append(files, *entry)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Cost != files[j].Cost {
+			return files[i].Cost > files[j].Cost
+		}
+		if files[i].Usage.TotalTokens != files[j].Usage.TotalTokens {
+			return files[i].Usage.TotalTokens > files[j].Usage.TotalTokens
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// WriteSummary prints the run totals and the most expensive files
+func (l *Ledger) WriteSummary(w io.Writer, topFiles int) {
+	total, cost := l.Total()
+	files := l.Files()
+
+	l.mu.Lock()
+	calls := l.calls
+	unpriced := make([]string, 0, len(l.unpriced))
+	for model := range l.unpriced {
+		unpriced = append(unpriced, model)
+	}
+	l.mu.Unlock()
+	sort.Strings(unpriced)
+
+	fmt.Fprintf(w, "LLM usage: %d calls for %d files\n", calls, len(files))
+	fmt.Fprintf(w, "  Tokens: %d prompt, %d completion, %d total\n", total.PromptTokens, total.CompletionTokens, total.TotalTokens)
+	fmt.Fprintf(w, "  Cost:   $%.4f\n", cost)
+	if len(unpriced) > 0 {
+		fmt.Fprintf(w, "  No price configured for: %s\n", strings.Join(unpriced, ", "))
+	}
+
+	if topFiles > len(files) {
+		topFiles = len(files)
+	}
+	if topFiles > 0 {
+		fmt.Fprintf(w, "  Most expensive files:\n")
+		for _, f := range files[:topFiles] {
+			fmt.Fprintf(w, "    $%.4f  %7d tokens  %s\n", f.Cost, f.Usage.TotalTokens, f.Path)
+		}
+	}
+}
+
+// MeteredProvider records the usage of every call in a Ledger, attributing
+// it to the file set on the call's context with WithFile
+type MeteredProvider struct {
+	Provider
+	ledger *Ledger
+}
+
+// NewMeteredProvider wraps inner so its usage is recorded in ledger
+func NewMeteredProvider(inner Provider, ledger *Ledger) *MeteredProvider {
+	return &MeteredProvider{
+		Provider: inner,
+		ledger:   ledger,
+	}
+}
+
+// Chat performs a chat completion and records its usage
+func (p *MeteredProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	resp, err := p.Provider.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	model := resp.Model
+	if model == "" {
+		model = p.Provider.Model()
+	}
+	p.ledger.Record(FileFromContext(ctx), model, resp.Usage)
+	return resp, nil
+}
+
+// Embed returns embeddings and records their usage
+func (p *MeteredProvider) Embed(ctx context.Context, inputs []string) (*EmbeddingResponse, error) {
+	resp, err := p.Provider.Embed(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	p.ledger.Record(FileFromContext(ctx), resp.Model, resp.Usage)
+	return resp, nil
+}
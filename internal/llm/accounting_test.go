@@ -0,0 +1,87 @@
+// autodoc/internal/llm/accounting_test.go
+
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+func TestPriceTableLookup(t *testing.T) {
+	table := NewPriceTable(map[string]config.ModelPrice{"local-model": {Prompt: 1}})
+
+	// The longest prefix wins, so gpt-4o-mini is not priced as gpt-4o
+	price, ok := table.Lookup("gpt-4o-mini-2024-07-18")
+	if !ok || price.Prompt != 0.15 {
+		t.Errorf("Expected gpt-4o-mini price, got %+v", price)
+	}
+	if _, ok := table.Lookup("local-model"); !ok {
+		t.Errorf("Expected override for local-model")
+	}
+	if _, ok := table.Lookup("unknown"); ok {
+		t.Errorf("Expected no price for unknown model")
+	}
+
+	cost := table.Cost("gpt-4o", Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000})
+	if math.Abs(cost-7.50) > 1e-9 {
+		t.Errorf("Expected cost 7.50, got %f", cost)
+	}
+}
+
+func TestMeteredProviderBudget(t *testing.T) {
+	prices := PriceTable{"stub-model": {Prompt: 1_000, Completion: 2_000}}
+	ledger := NewLedger(prices, 0, 0.01)
+	provider := NewMeteredProvider(&stubProvider{}, ledger)
+
+	// Each stub call costs 3*1000/1e6 + 1*2000/1e6 = $0.005
+	ctx := WithFile(context.Background(), "a.go")
+	if _, err := provider.Chat(ctx, ChatRequest{}); err != nil {
+		t.Fatalf("Failed to chat: %v", err)
+	}
+	if err := ledger.Check(); err != nil {
+		t.Errorf("Expected budget to remain, got %v", err)
+	}
+
+	ctx = WithFile(context.Background(), "b.go")
+	for i := 0; i < 2; i++ {
+		if _, err := provider.Chat(ctx, ChatRequest{}); err != nil {
+			t.Fatalf("Failed to chat: %v", err)
+		}
+	}
+	if err := ledger.Check(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+	}
+
+	usage, cost := ledger.Total()
+	if usage.TotalTokens != 12 {
+		t.Errorf("Expected 12 total tokens, got %d", usage.TotalTokens)
+	}
+	if math.Abs(cost-0.015) > 1e-9 {
+		t.Errorf("Expected cost 0.015, got %f", cost)
+	}
+
+	files := ledger.Files()
+	if len(files) != 2 || files[0].Path != "b.go" || files[0].Calls != 2 {
+		t.Errorf("Expected b.go to be the most expensive file, got %+v", files)
+	}
+
+	var buf bytes.Buffer
+	ledger.WriteSummary(&buf, 5)
+	if !strings.Contains(buf.String(), "$0.0150") {
+		t.Errorf("Expected summary to include total cost, got:\n%s", buf.String())
+	}
+}
+
+func TestLedgerTokenBudget(t *testing.T) {
+	ledger := NewLedger(nil, 10, 0)
+	ledger.Record("a.go", "gpt-4o", Usage{PromptTokens: 8, CompletionTokens: 2, TotalTokens: 10})
+	if err := ledger.Check(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+	}
+}
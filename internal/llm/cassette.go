@@ -0,0 +1,222 @@
+// autodoc/internal/llm/cassette.go
+
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+// ErrCassetteMiss is returned in replay mode when no recording matches a request
+var ErrCassetteMiss = errors.New("no cassette recorded for request")
+
+// cassette is the on-disk format of a single recorded interaction
+type cassette struct {
+	Key       string             `json:"key"`
+	Kind      string             `json:"kind"`
+	Chat      *ChatRequest       `json:"chat_request,omitempty"`
+	ChatResp  *ChatResponse      `json:"chat_response,omitempty"`
+	Inputs    []string           `json:"embedding_inputs,omitempty"`
+	EmbedResp *EmbeddingResponse `json:"embedding_response,omitempty"`
+}
+
+// CassetteProvider records provider traffic to disk or replays it from disk.
+// Recordings are keyed by a hash of the model and the normalized prompt, so
+// replay is deterministic and needs no network access or API key.
+type CassetteProvider struct {
+	inner          Provider
+	dir            string
+	mode           string
+	model          string
+	embeddingModel string
+	usageTracker
+}
+
+// NewCassetteProvider wraps inner with record or replay behavior. In replay
+// mode inner may be nil.
+func NewCassetteProvider(inner Provider, cfg config.LLMConfig) (*CassetteProvider, error) {
+	switch cfg.CassetteMode {
+	case config.CassetteRecord:
+		if inner == nil {
+			return nil, fmt.Errorf("record mode requires an underlying provider")
+		}
+		if err := os.MkdirAll(cfg.CassetteDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	case config.CassetteReplay:
+	default:
+		return nil, fmt.Errorf("unknown cassette mode: %s", cfg.CassetteMode)
+	}
+
+	return &CassetteProvider{
+		inner:          inner,
+		dir:            cfg.CassetteDir,
+		mode:           cfg.CassetteMode,
+		model:          cfg.Model,
+		embeddingModel: cfg.EmbeddingModel,
+	}, nil
+}
+
+// Name returns the provider name
+func (p *CassetteProvider) Name() string {
+	if p.inner != nil {
+		return fmt.Sprintf("%s (%s)", p.inner.Name(), p.mode)
+	}
+	return "cassette (" + p.mode + ")"
+}
+
+// Model returns the default chat model
+func (p *CassetteProvider) Model() string {
+	return p.model
+}
+
+// Chat records or replays a chat completion
+func (p *CassetteProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if req.Model == "" {
+		req.Model = p.model
+	}
+	key := ChatKey(req)
+
+	if p.mode == config.CassetteReplay {
+		c, err := p.load("chat", key)
+		if err != nil {
+			return nil, err
+		}
+		p.record(c.ChatResp.Usage)
+		return c.ChatResp, nil
+	}
+
+	resp, err := p.inner.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	p.record(resp.Usage)
+
+	if err := p.save(&cassette{Key: key, Kind: "chat", Chat: &req, ChatResp: resp}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Embed records or replays an embedding request
+func (p *CassetteProvider) Embed(ctx context.Context, inputs []string) (*EmbeddingResponse, error) {
+	key := EmbeddingKey(p.embeddingModel, inputs)
+
+	if p.mode == config.CassetteReplay {
+		c, err := p.load("embedding", key)
+		if err != nil {
+			return nil, err
+		}
+		p.record(c.EmbedResp.Usage)
+		return c.EmbedResp, nil
+	}
+
+	resp, err := p.inner.Embed(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	p.record(resp.Usage)
+
+	if err := p.save(&cassette{Key: key, Kind: "embedding", Inputs: inputs, EmbedResp: resp}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// load reads a recorded interaction from disk
+func (p *CassetteProvider) load(kind, key string) (*cassette, error) {
+	path := p.path(kind, key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrCassetteMiss, path)
+		}
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var c cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if (kind == "chat" && c.ChatResp == nil) || (kind == "embedding" && c.EmbedResp == nil) {
+		return nil, fmt.Errorf("cassette %s has no %s response", path, kind)
+	}
+	return &c, nil
+}
+
+// save writes a recorded interaction to disk atomically
+func (p *CassetteProvider) save(c *cassette) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+
+	path := p.path(c.Kind, c.Key)
+	tmp, err := os.CreateTemp(p.dir, ".cassette-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cassette: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// path returns the file path for a recording
+func (p *CassetteProvider) path(kind, key string) string {
+	return filepath.Join(p.dir, fmt.Sprintf("%s-%s.json", kind, key))
+}
+
+// ChatKey returns the cassette key for a chat request: a hash of the model,
+// the response format and the role and normalized content of every message
+func ChatKey(req ChatRequest) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "model:%s\n", req.Model)
+	if req.ResponseFormat != nil {
+		fmt.Fprintf(h, "format:%s\n", req.ResponseFormat.Name)
+	}
+	for _, msg := range req.Messages {
+		fmt.Fprintf(h, "%s:%s\n", msg.Role, normalizePrompt(msg.Content))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// EmbeddingKey returns the cassette key for an embedding request
+func EmbeddingKey(model string, inputs []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "model:%s\n", model)
+	for _, input := range inputs {
+		fmt.Fprintf(h, "input:%s\n", normalizePrompt(input))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// normalizePrompt removes formatting noise that should not change a cassette
+// key: line ending style, trailing whitespace and surrounding blank lines
+func normalizePrompt(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
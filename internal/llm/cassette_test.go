@@ -0,0 +1,90 @@
+// autodoc/internal/llm/cassette_test.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+// stubProvider returns a fixed response and counts calls
+type stubProvider struct {
+	calls int
+	usageTracker
+}
+
+func (s *stubProvider) Name() string  { return "stub" }
+func (s *stubProvider) Model() string { return "stub-model" }
+
+func (s *stubProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	s.calls++
+	return &ChatResponse{Content: "recorded", Model: req.Model, Usage: Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}}, nil
+}
+
+func (s *stubProvider) Embed(ctx context.Context, inputs []string) (*EmbeddingResponse, error) {
+	s.calls++
+	return &EmbeddingResponse{Embeddings: [][]float64{{0.1, 0.2}}, Model: "stub-embed"}, nil
+}
+
+func TestCassetteRecordReplay(t *testing.T) {
+	cfg := config.LLMConfig{
+		Model:          "stub-model",
+		EmbeddingModel: "stub-embed",
+		CassetteDir:    t.TempDir(),
+		CassetteMode:   config.CassetteRecord,
+	}
+
+	stub := &stubProvider{}
+	recorder, err := NewCassetteProvider(stub, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+
+	req := ChatRequest{Messages: []Message{SystemMessage("system"), UserMessage("analyze this\r\ncode  \n")}}
+	if _, err := recorder.Chat(context.Background(), req); err != nil {
+		t.Fatalf("Failed to record chat: %v", err)
+	}
+	if _, err := recorder.Embed(context.Background(), []string{"text"}); err != nil {
+		t.Fatalf("Failed to record embedding: %v", err)
+	}
+
+	cfg.CassetteMode = config.CassetteReplay
+	player, err := NewCassetteProvider(nil, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create player: %v", err)
+	}
+
+	// Formatting differences must not change the key
+	resp, err := player.Chat(context.Background(), ChatRequest{
+		Messages: []Message{SystemMessage("system"), UserMessage("analyze this\ncode")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to replay chat: %v", err)
+	}
+	if resp.Content != "recorded" {
+		t.Errorf("Expected recorded content, got %s", resp.Content)
+	}
+	if usage := player.Usage(); usage.TotalTokens != 4 {
+		t.Errorf("Expected replayed usage of 4 tokens, got %d", usage.TotalTokens)
+	}
+
+	embed, err := player.Embed(context.Background(), []string{"text"})
+	if err != nil {
+		t.Fatalf("Failed to replay embedding: %v", err)
+	}
+	if len(embed.Embeddings) != 1 {
+		t.Errorf("Expected 1 embedding, got %d", len(embed.Embeddings))
+	}
+
+	if stub.calls != 2 {
+		t.Errorf("Expected 2 calls to the underlying provider, got %d", stub.calls)
+	}
+
+	_, err = player.Chat(context.Background(), ChatRequest{Messages: []Message{UserMessage("something else")}})
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("Expected ErrCassetteMiss, got %v", err)
+	}
+}
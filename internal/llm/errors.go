@@ -0,0 +1,170 @@
+// autodoc/internal/llm/errors.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorClass tells callers how to handle a failed LLM call
+type ErrorClass int
+
+const (
+	// ErrorRetryable failures may succeed if the call is repeated later
+	ErrorRetryable ErrorClass = iota
+	// ErrorFatal failures will fail again no matter how often they are repeated
+	ErrorFatal
+	// ErrorTooLarge failures exceed the model's context window; the input must be split
+	ErrorTooLarge
+)
+
+// String returns the name of the error class
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorRetryable:
+		return "retryable"
+	case ErrorFatal:
+		return "fatal"
+	case ErrorTooLarge:
+		return "too-large"
+	default:
+		return fmt.Sprintf("ErrorClass(%d)", int(c))
+	}
+}
+
+// ErrTooLarge reports a request that does not fit the model's context window
+var ErrTooLarge = errors.New("request exceeds the model context window")
+
+// APIError is an error response from an LLM provider
+type APIError struct {
+	StatusCode int           // HTTP status code
+	Code       string        // Provider error code, e.g. "context_length_exceeded"
+	Message    string        // Provider error message
+	RetryAfter time.Duration // How long the provider asked us to wait, if it said
+	Err        error         // Underlying SDK error
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("status %d", e.StatusCode)
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports too-large API errors as ErrTooLarge
+func (e *APIError) Is(target error) bool {
+	return target == ErrTooLarge && e.tooLarge()
+}
+
+// tooLarge reports whether the provider rejected the request for its size
+func (e *APIError) tooLarge() bool {
+	if e.StatusCode == http.StatusRequestEntityTooLarge || e.Code == "context_length_exceeded" {
+		return true
+	}
+	msg := strings.ToLower(e.Message)
+	return e.StatusCode == http.StatusBadRequest &&
+		(strings.Contains(msg, "maximum context length") || strings.Contains(msg, "too many tokens"))
+}
+
+// Classify decides how a failed LLM call should be handled. Unknown errors,
+// such as network failures, are assumed to be retryable.
+func Classify(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return ErrorRetryable
+	case errors.Is(err, ErrTooLarge):
+		return ErrorTooLarge
+	case errors.Is(err, context.Canceled),
+		errors.Is(err, ErrCassetteMiss),
+		errors.Is(err, ErrBudgetExceeded):
+		return ErrorFatal
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return ErrorRetryable
+	}
+	switch {
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		// Exhausted quota is reported as a 429 but never recovers by waiting
+		if apiErr.Code == "insufficient_quota" {
+			return ErrorFatal
+		}
+		return ErrorRetryable
+	case apiErr.StatusCode == http.StatusRequestTimeout,
+		apiErr.StatusCode == http.StatusConflict,
+		apiErr.StatusCode >= 500:
+		return ErrorRetryable
+	default:
+		return ErrorFatal
+	}
+}
+
+// StatusCode returns the HTTP status code of a provider error, or 0
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// RetryAfterOf returns how long the provider asked us to wait before retrying, or 0
+func RetryAfterOf(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter reads the wait time from Retry-After style headers. Rate
+// limit reset headers are only used when no explicit Retry-After is given.
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if header == nil {
+		return 0
+	}
+
+	if ms := header.Get("Retry-After-Ms"); ms != "" {
+		if v, err := strconv.ParseFloat(ms, 64); err == nil && v > 0 {
+			return time.Duration(v * float64(time.Millisecond))
+		}
+	}
+
+	// Retry-After is either a number of seconds or an HTTP date
+	if ra := header.Get("Retry-After"); ra != "" {
+		if v, err := strconv.ParseFloat(ra, 64); err == nil && v > 0 {
+			return time.Duration(v * float64(time.Second))
+		}
+		if t, err := http.ParseTime(ra); err == nil && t.After(now) {
+			return t.Sub(now)
+		}
+	}
+
+	// Reset headers use Go-style durations such as "1s" or "6m0s"
+	var wait time.Duration
+	for _, name := range []string{"X-Ratelimit-Reset-Requests", "X-Ratelimit-Reset-Tokens"} {
+		if v := header.Get(name); v != "" {
+			if d, err := time.ParseDuration(v); err == nil && d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
@@ -0,0 +1,202 @@
+// autodoc/internal/llm/openai.go
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+// OpenAIProvider implements Provider on top of the OpenAI API
+type OpenAIProvider struct {
+	client *openai.Client
+	cfg    config.LLMConfig
+	usageTracker
+}
+
+// NewOpenAIProvider creates a new provider for the OpenAI API or any
+// OpenAI-compatible endpoint (vLLM, Ollama, llama.cpp server, ...)
+func NewOpenAIProvider(cfg config.LLMConfig) *OpenAIProvider {
+	return &OpenAIProvider{
+		client: openai.NewClient(clientOptions(cfg)...),
+		cfg:    cfg,
+	}
+}
+
+// clientOptions builds the SDK request options for the given configuration
+func clientOptions(cfg config.LLMConfig) []option.RequestOption {
+	var opts []option.RequestOption
+	if cfg.APIKey != "" {
+		opts = append(opts, option.WithAPIKey(cfg.APIKey))
+	} else {
+		// The SDK picks up OPENAI_API_KEY on its own; keyless endpoints get no auth header
+		opts = append(opts, option.WithHeaderDel("authorization"))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+	}
+	for key, value := range cfg.Headers {
+		opts = append(opts, option.WithHeader(key, value))
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, option.WithRequestTimeout(cfg.Timeout))
+	}
+	// Retries are left to the caller, which classifies errors and honors Retry-After
+	opts = append(opts, option.WithMaxRetries(0))
+	return opts
+}
+
+// apiError converts SDK errors into an APIError carrying retry information
+func apiError(err error) error {
+	var sdkErr *openai.Error
+	if !errors.As(err, &sdkErr) {
+		return err
+	}
+	apiErr := &APIError{
+		StatusCode: sdkErr.StatusCode,
+		Code:       sdkErr.Code,
+		Message:    sdkErr.Message,
+		Err:        err,
+	}
+
+	// The API nests the details under "error", which the SDK does not unwrap
+	if apiErr.Code == "" && apiErr.Message == "" {
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+				Type    string `json:"type"`
+			} `json:"error"`
+		}
+		if json.Unmarshal([]byte(sdkErr.JSON.RawJSON()), &body) == nil {
+			apiErr.Code = body.Error.Code
+			if apiErr.Code == "" {
+				apiErr.Code = body.Error.Type
+			}
+			apiErr.Message = body.Error.Message
+		}
+	}
+	if sdkErr.Response != nil {
+		apiErr.RetryAfter = parseRetryAfter(sdkErr.Response.Header, time.Now())
+	}
+	return apiErr
+}
+
+// Name returns the provider name
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+// Model returns the default chat model
+func (p *OpenAIProvider) Model() string {
+	return p.cfg.Model
+}
+
+// Chat performs a chat completion
+func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case RoleSystem:
+			messages = append(messages, openai.SystemMessage(msg.Content))
+		case RoleAssistant:
+			messages = append(messages, openai.AssistantMessage(msg.Content))
+		default:
+			messages = append(messages, openai.UserMessage(msg.Content))
+		}
+	}
+
+	model := req.Model
+	if model == "" {
+		model = p.cfg.Model
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Messages: openai.F(messages),
+		Model:    openai.F(model),
+	}
+
+	temperature := p.cfg.Temperature
+	if req.Temperature != nil {
+		temperature = *req.Temperature
+	}
+	params.Temperature = openai.F(temperature)
+
+	maxTokens := p.cfg.MaxTokens
+	if req.MaxTokens > 0 {
+		maxTokens = req.MaxTokens
+	}
+	if maxTokens > 0 {
+		params.MaxTokens = openai.F(maxTokens)
+	}
+
+	if req.ResponseFormat != nil && p.cfg.StructuredOutput {
+		params.ResponseFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](openai.ResponseFormatJSONSchemaParam{
+			Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+			JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   openai.F(req.ResponseFormat.Name),
+				Schema: openai.F[interface{}](req.ResponseFormat.Schema),
+				Strict: openai.F(req.ResponseFormat.Strict),
+			}),
+		})
+	}
+
+	resp, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API error: %w", apiError(err))
+	}
+
+	usage := Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
+	}
+	p.record(usage)
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no completion choices returned")
+	}
+
+	return &ChatResponse{
+		Content: resp.Choices[0].Message.Content,
+		Model:   resp.Model,
+		Usage:   usage,
+	}, nil
+}
+
+// Embed returns embeddings for the given inputs
+func (p *OpenAIProvider) Embed(ctx context.Context, inputs []string) (*EmbeddingResponse, error) {
+	resp, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input: openai.F[openai.EmbeddingNewParamsInputUnion](openai.EmbeddingNewParamsInputArrayOfStrings(inputs)),
+		Model: openai.F(p.cfg.EmbeddingModel),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI embedding error: %w", apiError(err))
+	}
+
+	usage := Usage{
+		PromptTokens: resp.Usage.PromptTokens,
+		TotalTokens:  resp.Usage.TotalTokens,
+	}
+	p.record(usage)
+
+	embeddings := make([][]float64, len(resp.Data))
+	for _, data := range resp.Data {
+		if int(data.Index) < len(embeddings) {
+			embeddings[data.Index] = data.Embedding
+		}
+	}
+
+	return &EmbeddingResponse{
+		Embeddings: embeddings,
+		Model:      resp.Model,
+		Usage:      usage,
+	}, nil
+}
@@ -0,0 +1,165 @@
+// autodoc/internal/llm/openai_test.go
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+func TestOpenAIProviderCustomEndpoint(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+
+	var gotPath, gotAuth, gotHeader, gotModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotHeader = r.Header.Get("X-Team")
+
+		var body struct {
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		gotModel = body.Model
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"created": 0,
+			"model": "local-model",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "hello"}}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 2, "total_tokens": 7}
+		}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIProvider(config.LLMConfig{
+		BaseURL: server.URL + "/v1/",
+		Headers: map[string]string{"X-Team": "docs"},
+		Model:   "local-model",
+	})
+
+	resp, err := provider.Chat(context.Background(), ChatRequest{
+		Messages: []Message{UserMessage("hi")},
+	})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if gotPath != "/v1/chat/completions" {
+		t.Errorf("Expected path /v1/chat/completions, got %s", gotPath)
+	}
+	if gotAuth != "" {
+		t.Errorf("Expected no Authorization header, got %q", gotAuth)
+	}
+	if gotHeader != "docs" {
+		t.Errorf("Expected X-Team header docs, got %q", gotHeader)
+	}
+	if gotModel != "local-model" {
+		t.Errorf("Expected model local-model, got %s", gotModel)
+	}
+	if resp.Content != "hello" {
+		t.Errorf("Expected content hello, got %s", resp.Content)
+	}
+	if usage := provider.Usage(); usage.TotalTokens != 7 {
+		t.Errorf("Expected 7 total tokens, got %d", usage.TotalTokens)
+	}
+}
+
+func TestOpenAIProviderErrorClassification(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+
+	tests := []struct {
+		name       string
+		status     int
+		header     map[string]string
+		body       string
+		class      ErrorClass
+		retryAfter time.Duration
+	}{
+		{
+			name:       "rate limited",
+			status:     http.StatusTooManyRequests,
+			header:     map[string]string{"Retry-After": "3"},
+			body:       `{"error": {"message": "Rate limit reached", "type": "requests", "code": "rate_limit_exceeded"}}`,
+			class:      ErrorRetryable,
+			retryAfter: 3 * time.Second,
+		},
+		{
+			name:       "rate limit reset header",
+			status:     http.StatusTooManyRequests,
+			header:     map[string]string{"X-Ratelimit-Reset-Tokens": "1m30s"},
+			body:       `{"error": {"message": "Rate limit reached", "type": "tokens", "code": "rate_limit_exceeded"}}`,
+			class:      ErrorRetryable,
+			retryAfter: 90 * time.Second,
+		},
+		{
+			name:   "quota exhausted",
+			status: http.StatusTooManyRequests,
+			body:   `{"error": {"message": "You exceeded your current quota", "type": "insufficient_quota", "code": "insufficient_quota"}}`,
+			class:  ErrorFatal,
+		},
+		{
+			name:   "unauthorized",
+			status: http.StatusUnauthorized,
+			body:   `{"error": {"message": "Incorrect API key provided", "type": "invalid_request_error", "code": "invalid_api_key"}}`,
+			class:  ErrorFatal,
+		},
+		{
+			name:   "context length",
+			status: http.StatusBadRequest,
+			body:   `{"error": {"message": "This model's maximum context length is 8192 tokens", "type": "invalid_request_error", "code": "context_length_exceeded"}}`,
+			class:  ErrorTooLarge,
+		},
+		{
+			name:   "server error",
+			status: http.StatusBadGateway,
+			body:   `{"error": {"message": "Bad gateway", "type": "server_error", "code": null}}`,
+			class:  ErrorRetryable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				for key, value := range tt.header {
+					w.Header().Set(key, value)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewOpenAIProvider(config.LLMConfig{BaseURL: server.URL + "/v1/", Model: "m"})
+			_, err := provider.Chat(context.Background(), ChatRequest{Messages: []Message{UserMessage("hi")}})
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+
+			if class := Classify(err); class != tt.class {
+				t.Errorf("Expected class %s, got %s (%v)", tt.class, class, err)
+			}
+			if got := RetryAfterOf(err); got != tt.retryAfter {
+				t.Errorf("Expected retry after %s, got %s", tt.retryAfter, got)
+			}
+			if StatusCode(err) != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, StatusCode(err))
+			}
+			if calls != 1 {
+				t.Errorf("Expected the SDK not to retry, got %d calls", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// autodoc/internal/llm/provider.go
+
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+// Message roles understood by every provider
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message represents a single chat message
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SystemMessage creates a system message
+func SystemMessage(content string) Message {
+	return Message{Role: RoleSystem, Content: content}
+}
+
+// UserMessage creates a user message
+func UserMessage(content string) Message {
+	return Message{Role: RoleUser, Content: content}
+}
+
+// AssistantMessage creates an assistant message
+func AssistantMessage(content string) Message {
+	return Message{Role: RoleAssistant, Content: content}
+}
+
+// ChatRequest describes a chat completion request. Zero-valued fields fall
+// back to the provider's configured defaults.
+type ChatRequest struct {
+	Messages       []Message       `json:"messages"`
+	Model          string          `json:"model,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	MaxTokens      int64           `json:"max_tokens,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat asks the provider to constrain its output to a JSON schema
+type ResponseFormat struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+	Strict bool           `json:"strict"`
+}
+
+// ChatResponse holds the result of a chat completion
+type ChatResponse struct {
+	Content string `json:"content"`
+	Model   string `json:"model"`
+	Usage   Usage  `json:"usage"`
+}
+
+// EmbeddingResponse holds the result of an embedding request
+type EmbeddingResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+	Model      string      `json:"model"`
+	Usage      Usage       `json:"usage"`
+}
+
+// Usage reports token consumption
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// Add returns the sum of two usage values
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// Provider is the interface every LLM backend implements. All analysis paths
+// go through a Provider rather than talking to a vendor SDK directly.
+type Provider interface {
+	// Name returns the provider name (e.g., "openai")
+	Name() string
+	// Model returns the default chat model
+	Model() string
+	// Chat performs a chat completion
+	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+	// Embed returns embeddings for the given inputs
+	Embed(ctx context.Context, inputs []string) (*EmbeddingResponse, error)
+	// Usage returns the cumulative token usage of this provider
+	Usage() Usage
+}
+
+// NewProvider creates the provider named in the configuration, wrapped in a
+// cassette recorder or player when a cassette mode is configured
+func NewProvider(cfg config.LLMConfig) (Provider, error) {
+	if cfg.CassetteMode == config.CassetteReplay {
+		return NewCassetteProvider(nil, cfg)
+	}
+
+	provider, err := newBaseProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.CassetteMode != "" {
+		return NewCassetteProvider(provider, cfg)
+	}
+	return provider, nil
+}
+
+// newBaseProvider creates the provider that talks to the actual backend
+func newBaseProvider(cfg config.LLMConfig) (Provider, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "", "openai":
+		return NewOpenAIProvider(cfg), nil
+	case config.ProviderOffline:
+		return nil, fmt.Errorf("the %s provider performs static analysis and has no LLM backend", config.ProviderOffline)
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", cfg.Provider)
+	}
+}
+
+// usageTracker accumulates token usage across calls and is safe for concurrent use
+type usageTracker struct {
+	mu    sync.Mutex
+	total Usage
+}
+
+// record adds usage to the running total
+func (t *usageTracker) record(u Usage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = t.total.Add(u)
+}
+
+// Usage returns the running total
+func (t *usageTracker) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
@@ -0,0 +1,65 @@
+// autodoc/internal/llm/tokens.go
+
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultContextWindow is used for models missing from the context window table
+const DefaultContextWindow = 8192
+
+// contextWindows maps model name prefixes to their context window in tokens.
+// Longer prefixes are listed first so they win over shorter ones.
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o-mini", 128000},
+	{"gpt-4o", 128000},
+	{"chatgpt-4o", 128000},
+	{"gpt-4.1", 1047576},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 200000},
+	{"o3", 200000},
+}
+
+// ContextWindow returns the context window of a model in tokens
+func ContextWindow(model string) int {
+	model = strings.ToLower(model)
+	for _, entry := range contextWindows {
+		if strings.HasPrefix(model, entry.prefix) {
+			return entry.tokens
+		}
+	}
+	return DefaultContextWindow
+}
+
+// EstimateTokens approximates the number of tokens in text. It assumes about
+// four characters per token for prose and code, which errs on the high side
+// for typical source files, and never returns less than one token per line.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	byChars := (utf8.RuneCountInString(text) + 3) / 4
+	byLines := strings.Count(text, "\n") + 1
+	if byLines > byChars {
+		return byLines
+	}
+	return byChars
+}
+
+// EstimateRequestTokens approximates the prompt tokens of a chat request,
+// including a small per-message overhead
+func EstimateRequestTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += EstimateTokens(msg.Content) + 4
+	}
+	return total
+}
@@ -0,0 +1,112 @@
+// autodoc/internal/pipeline/failures.go
+
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/storage"
+)
+
+// Pipeline stages, as recorded in failures and progress events
+const (
+	StageCollect = "collect"
+	StageAnalyze = "analyze"
+	StageResolve = "resolve"
+	StagePersist = "persist"
+	StageRender  = "render"
+)
+
+// maxRawInPage limits how much of a raw response a failure page shows
+const maxRawInPage = 4000
+
+// StageError records that a stage failed for one file
+type StageError struct {
+	Path  string
+	Stage string
+	Raw   string // Raw analyzer response, when there was one
+	Err   error
+}
+
+// Error implements the error interface
+func (e *StageError) Error() string {
+	return fmt.Sprintf("failed to %s %s: %v", e.Stage, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// FailedStage returns the stage that failed, so checkpoints can record it
+func (e *StageError) FailedStage() string {
+	return e.Stage
+}
+
+// RawResponse returns the raw analyzer response, so checkpoints can record it
+func (e *StageError) RawResponse() string {
+	return e.Raw
+}
+
+// FailurePage creates the page rendered in place of a file that could not
+// be documented
+func FailurePage(file collector.FileInfo, failure *StageError) *storage.Document {
+	var b strings.Builder
+	b.WriteString("## Failed to document\n\n")
+	fmt.Fprintf(&b, "AutoDoc could not document `%s`.\n\n", file.Path)
+	fmt.Fprintf(&b, "- Stage: %s\n", failure.Stage)
+	fmt.Fprintf(&b, "- Error: %v\n", failure.Err)
+	if failure.Raw != "" {
+		raw := failure.Raw
+		if len(raw) > maxRawInPage {
+			// Cut at the start of a rune so the page stays valid UTF-8
+			n := maxRawInPage
+			for n > 0 && !utf8.RuneStart(raw[n]) {
+				n--
+			}
+			raw = raw[:n] + "\n..."
+		}
+		fence := codeFence(raw)
+		b.WriteString("\n### Raw response\n\n" + fence + "\n" + raw + "\n" + fence + "\n")
+	}
+	b.WriteString("\nRun `autodoc retry-failed` to try again.\n")
+
+	doc := NewDocument(file, b.String(), "")
+	doc.Purpose = "Failed to document"
+	return doc
+}
+
+// codeFence returns a backtick fence longer than any run of backticks in
+// text, so fences inside the text cannot close the block
+func codeFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// WriteFailureReport writes one line per failed file: its path, the stage
+// that failed and the error
+func WriteFailureReport(w io.Writer, failures []storage.FileState) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Failed files (%d):\n", len(failures))
+	for _, failure := range failures {
+		stage := failure.Stage
+		if stage == "" {
+			stage = StageAnalyze
+		}
+		fmt.Fprintf(w, "  %s [%s] %s\n", failure.Path, stage, failure.Error)
+	}
+}
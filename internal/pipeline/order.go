@@ -0,0 +1,296 @@
+// autodoc/internal/pipeline/order.go
+
+package pipeline
+
+import (
+	"go/parser"
+	"go/token"
+	"log"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+)
+
+var (
+	goModulePattern       = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+	csNamespacePattern    = regexp.MustCompile(`(?m)^\s*namespace\s+([\w.]+)`)
+	csUsingPattern        = regexp.MustCompile(`(?m)^\s*(?:global\s+)?using\s+(?:static\s+)?([\w.]+)\s*;`)
+	csProjectRefPattern   = regexp.MustCompile(`<ProjectReference\s+Include="([^"]+)"`)
+	slnProjectPathPattern = regexp.MustCompile(`"([^"]+\.csproj)"`)
+)
+
+// ImportGraph maps the path of each collected file to the paths of the
+// collected files it imports
+type ImportGraph map[string][]string
+
+// BuildImportGraph finds the imports between files under root. Go imports
+// are resolved through the collected go.mod files, or by matching the end of
+// the import path against the package directories when there are none. C#
+// sources depend on the files declaring the namespaces they use, and project
+// and solution files on the projects they reference. Files without content
+// are read one at a time, and only their import declarations are kept.
+func BuildImportGraph(root string, files []collector.FileInfo) ImportGraph {
+	graph := make(ImportGraph)
+	newImportIndex(root).addTo(graph, files)
+	return graph
+}
+
+// addTo indexes files and adds their imports to graph. Imports resolve to
+// every file indexed so far, so files added in a later call can depend on
+// these but not the other way around.
+func (x *importIndex) addTo(graph ImportGraph, files []collector.FileInfo) {
+	decls := make([]*importDecls, len(files))
+	for i, file := range files {
+		decls[i] = x.add(file)
+	}
+
+	for i, file := range files {
+		var deps []string
+		switch {
+		case file.Language == "go" && file.Type == "source":
+			deps = x.goImports(file, decls[i])
+		case file.Language == "csharp" && file.Type == "source":
+			deps = x.csharpUsings(decls[i])
+		case file.Language == "csharp":
+			deps = x.projectReferences(file, decls[i])
+		}
+
+		// Keep each dependency once, never the file itself
+		seen := map[string]bool{file.Path: true}
+		for _, dep := range deps {
+			if !seen[dep] {
+				seen[dep] = true
+				graph[file.Path] = append(graph[file.Path], dep)
+			}
+		}
+		sort.Strings(graph[file.Path])
+	}
+}
+
+// Order returns files sorted so that every file comes after the files it
+// imports, leaves first. Files in an import cycle are ordered by path, so
+// the cycle is always broken in the same place; unrelated files keep their
+// collection order.
+func (g ImportGraph) Order(files []collector.FileInfo) []collector.FileInfo {
+	byPath := make(map[string]collector.FileInfo, len(files))
+	for _, file := range files {
+		byPath[file.Path] = file
+	}
+
+	// Tarjan's algorithm emits each strongly connected component after
+	// every component it depends on
+	var (
+		ordered = make([]collector.FileInfo, 0, len(files))
+		index   = make(map[string]int)
+		lowlink = make(map[string]int)
+		onStack = make(map[string]bool)
+		stack   []string
+		visit   func(path string)
+	)
+	visit = func(p string) {
+		index[p] = len(index)
+		lowlink[p] = index[p]
+		stack = append(stack, p)
+		onStack[p] = true
+
+		for _, dep := range g[p] {
+			if _, ok := byPath[dep]; !ok {
+				continue
+			}
+			if _, seen := index[dep]; !seen {
+				visit(dep)
+				lowlink[p] = min(lowlink[p], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[p] = min(lowlink[p], index[dep])
+			}
+		}
+
+		if lowlink[p] != index[p] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == p {
+				break
+			}
+		}
+		sort.Strings(component)
+		for _, member := range component {
+			ordered = append(ordered, byPath[member])
+		}
+	}
+
+	for _, file := range files {
+		if _, seen := index[file.Path]; !seen {
+			visit(file.Path)
+		}
+	}
+	return ordered
+}
+
+// importDecls holds the import declarations of one file
+type importDecls struct {
+	imports    []string // Go import paths, C# using directives or referenced project paths
+	namespaces []string // C# namespaces the file declares
+}
+
+// importIndex resolves import declarations to collected files
+type importIndex struct {
+	root       string
+	goModules  map[string]string   // Module path to module directory
+	goPackages map[string][]string // Package directory to its Go sources
+	namespaces map[string][]string // C# namespace to the files declaring it
+	files      map[string]bool     // Every collected path, cleaned
+}
+
+// newImportIndex creates an empty index for files under root
+func newImportIndex(root string) *importIndex {
+	return &importIndex{
+		root:       root,
+		goModules:  make(map[string]string),
+		goPackages: make(map[string][]string),
+		namespaces: make(map[string][]string),
+		files:      make(map[string]bool),
+	}
+}
+
+// add indexes file and returns its import declarations
+func (x *importIndex) add(file collector.FileInfo) *importDecls {
+	x.files[filepath.Clean(file.Path)] = true
+	if file.Language == "go" && file.Type == "source" {
+		dir := filepath.Dir(file.Path)
+		x.goPackages[dir] = append(x.goPackages[dir], file.Path)
+	}
+
+	decls := &importDecls{}
+	if file.Language != "go" && file.Language != "csharp" {
+		return decls
+	}
+	file, err := file.Load()
+	if err != nil {
+		log.Printf("Skipping imports of %s: %v", file.Path, err)
+		return decls
+	}
+
+	switch {
+	case file.Language == "go" && file.Type == "module":
+		if match := goModulePattern.FindStringSubmatch(file.Content); match != nil {
+			x.goModules[match[1]] = filepath.Dir(file.Path)
+		}
+	case file.Language == "go" && file.Type == "source":
+		parsed, err := parser.ParseFile(token.NewFileSet(), file.Path, file.Content, parser.ImportsOnly)
+		if err != nil {
+			return decls
+		}
+		for _, spec := range parsed.Imports {
+			if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+				decls.imports = append(decls.imports, importPath)
+			}
+		}
+	case file.Language == "csharp" && file.Type == "source":
+		for _, match := range csNamespacePattern.FindAllStringSubmatch(file.Content, -1) {
+			decls.namespaces = append(decls.namespaces, match[1])
+			x.namespaces[match[1]] = append(x.namespaces[match[1]], file.Path)
+		}
+		for _, match := range csUsingPattern.FindAllStringSubmatch(file.Content, -1) {
+			decls.imports = append(decls.imports, match[1])
+		}
+	case file.Language == "csharp":
+		pattern := csProjectRefPattern
+		if file.Type == "solution" {
+			pattern = slnProjectPathPattern
+		}
+		for _, match := range pattern.FindAllStringSubmatch(file.Content, -1) {
+			decls.imports = append(decls.imports, match[1])
+		}
+	}
+	return decls
+}
+
+// goImports returns the sources of the collected packages file imports
+func (x *importIndex) goImports(file collector.FileInfo, decls *importDecls) []string {
+	own := filepath.Dir(file.Path)
+	var deps []string
+	for _, importPath := range decls.imports {
+		if dir, ok := x.goPackageDir(importPath); ok && dir != own {
+			deps = append(deps, x.goPackages[dir]...)
+		}
+	}
+	return deps
+}
+
+// goPackageDir returns the collected package directory of importPath
+func (x *importIndex) goPackageDir(importPath string) (string, bool) {
+	if len(x.goModules) > 0 {
+		// The longest matching module path wins, for nested modules
+		best, bestDir := "", ""
+		for module, dir := range x.goModules {
+			if (importPath == module || strings.HasPrefix(importPath, module+"/")) && len(module) > len(best) {
+				best, bestDir = module, dir
+			}
+		}
+		if best == "" {
+			return "", false
+		}
+		dir := filepath.Join(bestDir, filepath.FromSlash(strings.TrimPrefix(importPath[len(best):], "/")))
+		_, ok := x.goPackages[dir]
+		return dir, ok
+	}
+
+	// Without go.mod, match the import path against the package directories
+	var dirs []string
+	for dir := range x.goPackages {
+		rel, err := filepath.Rel(x.root, dir)
+		if err != nil || rel == "." {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if importPath == rel || strings.HasSuffix(importPath, "/"+rel) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return "", false
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i]) > len(dirs[j]) || (len(dirs[i]) == len(dirs[j]) && dirs[i] < dirs[j])
+	})
+	return dirs[0], true
+}
+
+// csharpUsings returns the files declaring the namespaces a C# source uses
+func (x *importIndex) csharpUsings(decls *importDecls) []string {
+	own := make(map[string]bool)
+	for _, namespace := range decls.namespaces {
+		own[namespace] = true
+	}
+	var deps []string
+	for _, namespace := range decls.imports {
+		if !own[namespace] {
+			deps = append(deps, x.namespaces[namespace]...)
+		}
+	}
+	return deps
+}
+
+// projectReferences returns the projects a C# project or solution references
+func (x *importIndex) projectReferences(file collector.FileInfo, decls *importDecls) []string {
+	var deps []string
+	for _, ref := range decls.imports {
+		ref = path.Clean(strings.ReplaceAll(ref, `\`, "/"))
+		target := filepath.Join(filepath.Dir(file.Path), filepath.FromSlash(ref))
+		if x.files[target] {
+			deps = append(deps, target)
+		}
+	}
+	return deps
+}
@@ -0,0 +1,242 @@
+// autodoc/internal/pipeline/order_test.go
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/storage"
+)
+
+// goFile returns a collected Go file under root
+func goFile(root, path, content string) collector.FileInfo {
+	fileType := "source"
+	if filepath.Base(path) == "go.mod" {
+		fileType = "module"
+	}
+	return collector.FileInfo{Path: filepath.Join(root, path), Language: "go", Type: fileType, Content: content}
+}
+
+// orderOf returns the paths of files relative to root, joined by commas
+func orderOf(root string, files []collector.FileInfo) string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		rel, _ := filepath.Rel(root, file.Path)
+		paths[i] = filepath.ToSlash(rel)
+	}
+	return strings.Join(paths, ",")
+}
+
+func TestImportGraphOrdersGoPackages(t *testing.T) {
+	root := "/src"
+	files := []collector.FileInfo{
+		goFile(root, "cmd/app/main.go", "package main\n\nimport (\n\t\"fmt\"\n\t\"example.com/app/internal/server\"\n)\n"),
+		goFile(root, "go.mod", "module example.com/app\n\ngo 1.23\n"),
+		goFile(root, "internal/server/server.go", "package server\n\nimport \"example.com/app/internal/storage\"\n"),
+		goFile(root, "internal/server/routes.go", "package server\n"),
+		goFile(root, "internal/storage/store.go", "package storage\n\nimport \"os\"\n"),
+	}
+
+	graph := BuildImportGraph(root, files)
+	deps := graph[filepath.Join(root, "cmd/app/main.go")]
+	if len(deps) != 2 {
+		t.Errorf("Expected main.go to import both server files, got %v", deps)
+	}
+
+	got := orderOf(root, graph.Order(files))
+	want := "internal/server/routes.go,internal/storage/store.go,internal/server/server.go,cmd/app/main.go,go.mod"
+	if got != want {
+		t.Errorf("Expected order %s, got %s", want, got)
+	}
+}
+
+func TestImportGraphWithoutModule(t *testing.T) {
+	root := "/src"
+	files := []collector.FileInfo{
+		goFile(root, "web/web.go", "package web\n\nimport \"github.com/someone/app/store\"\n"),
+		goFile(root, "store/store.go", "package store\n"),
+	}
+
+	got := orderOf(root, BuildImportGraph(root, files).Order(files))
+	if got != "store/store.go,web/web.go" {
+		t.Errorf("Expected store before web, got %s", got)
+	}
+}
+
+func TestImportGraphBreaksCyclesByPath(t *testing.T) {
+	root := "/src"
+	cs := func(path, content string) collector.FileInfo {
+		return collector.FileInfo{Path: filepath.Join(root, path), Language: "csharp", Type: "source", Content: content}
+	}
+	files := []collector.FileInfo{
+		cs("Orders.cs", "using Shop.Billing;\nnamespace Shop.Orders { }\n"),
+		cs("Billing.cs", "using Shop.Orders;\nusing Shop.Core;\nnamespace Shop.Billing { }\n"),
+		cs("Core.cs", "using System;\nnamespace Shop.Core { }\n"),
+	}
+
+	// Orders and Billing import each other; both need Core
+	for i := 0; i < 2; i++ {
+		got := orderOf(root, BuildImportGraph(root, files).Order(files))
+		if got != "Core.cs,Billing.cs,Orders.cs" {
+			t.Errorf("Expected Core first and the cycle sorted by path, got %s", got)
+		}
+		files[0], files[1] = files[1], files[0]
+	}
+}
+
+// contextRecorder records the dependencies given to each analysis
+type contextRecorder struct {
+	*fakeStages
+	deps map[string][]collector.Dependency
+	mu   sync.Mutex
+}
+
+func (c *contextRecorder) Analyze(ctx context.Context, file collector.FileInfo) (*Result, error) {
+	// Give files without imports time to finish out of order
+	if len(file.Dependencies) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deps[file.Path] = file.Dependencies
+	doc := NewDocument(file, "doc", "v2")
+	doc.Purpose = "Purpose of " + filepath.Base(file.Path)
+	return &Result{File: file, Document: doc}, nil
+}
+
+func TestPipelineGivesDependencyPurposes(t *testing.T) {
+	root := "/src"
+	f := newFakeStages()
+	f.files = []collector.FileInfo{
+		goFile(root, "go.mod", "module example.com/app\n"),
+		goFile(root, "app.go", "package main\n\nimport \"example.com/app/store\"\n"),
+		goFile(root, "store/store.go", "package store\n"),
+	}
+	recorder := &contextRecorder{fakeStages: f, deps: make(map[string][]collector.Dependency)}
+
+	// Analyzed concurrently, app.go still waits for store.go
+	p := New(f, recorder, f, f, f)
+	p.SetWorkers(4)
+	if _, err := p.Run(context.Background(), root); err != nil {
+		t.Fatalf("Failed to run pipeline: %v", err)
+	}
+
+	deps := recorder.deps[filepath.Join(root, "app.go")]
+	if len(deps) != 1 || deps[0].Path != "store/store.go" || deps[0].Purpose != "Purpose of store.go" {
+		t.Errorf("Expected app.go to get the purpose of store.go, got %+v", deps)
+	}
+	if len(recorder.deps[filepath.Join(root, "store/store.go")]) != 0 {
+		t.Error("Expected store.go to get no dependencies")
+	}
+	if f.stored[filepath.Join(root, "store/store.go")] == nil {
+		t.Error("Expected store.go to be stored")
+	}
+}
+
+// pacedCollector sends its first files and holds back the rest until the
+// first analysis finishes or a second passes, then fails with err, if set
+type pacedCollector struct {
+	files    []collector.FileInfo
+	hold     int
+	analyzed chan struct{}
+	early    bool // The first analysis finished while files were held back
+	err      error
+}
+
+func (c *pacedCollector) Collect(ctx context.Context, root string) (<-chan collector.FileInfo, <-chan error) {
+	files := make(chan collector.FileInfo)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(files)
+		for i, file := range c.files {
+			if i == c.hold {
+				select {
+				case <-c.analyzed:
+					c.early = true
+				case <-time.After(time.Second):
+				}
+			}
+			select {
+			case files <- file:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+		if c.err != nil {
+			errs <- c.err
+		}
+	}()
+	return files, errs
+}
+
+// signalingRecorder is a contextRecorder that signals the first analysis
+type signalingRecorder struct {
+	*contextRecorder
+	once     sync.Once
+	analyzed chan struct{}
+}
+
+func (s *signalingRecorder) Analyze(ctx context.Context, file collector.FileInfo) (*Result, error) {
+	result, err := s.contextRecorder.Analyze(ctx, file)
+	s.once.Do(func() { close(s.analyzed) })
+	return result, err
+}
+
+func TestPipelineAnalyzesWhileCollecting(t *testing.T) {
+	root := "/src"
+	f := newFakeStages()
+	analyzed := make(chan struct{})
+	c := &pacedCollector{
+		files: []collector.FileInfo{
+			goFile(root, "store/store.go", "package store\n"),
+			goFile(root, "util/util.go", "package util\n"),
+			goFile(root, "app.go", "package main\n\nimport \"example.com/app/store\"\n"),
+		},
+		hold:     2,
+		analyzed: analyzed,
+	}
+	recorder := &signalingRecorder{
+		contextRecorder: &contextRecorder{fakeStages: f, deps: make(map[string][]collector.Dependency)},
+		analyzed:        analyzed,
+	}
+
+	// The first window is analyzed before the walk sends app.go
+	p := New(c, recorder, f, f, f)
+	p.SetOrderWindow(2)
+	status, err := p.Run(context.Background(), root)
+	if err != nil || status != storage.RunCompleted {
+		t.Fatalf("Expected a completed run, got %s: %v", status, err)
+	}
+	if !c.early {
+		t.Error("Expected the first file to be analyzed before the walk finished")
+	}
+
+	// app.go, in a later window, still gets the purpose of store.go
+	deps := recorder.deps[filepath.Join(root, "app.go")]
+	if len(deps) != 1 || deps[0].Path != "store/store.go" {
+		t.Errorf("Expected app.go to get the purpose of store.go, got %+v", deps)
+	}
+	if len(f.rendered) != 3 {
+		t.Errorf("Expected 3 pages rendered, got %d", len(f.rendered))
+	}
+
+	// A walk that fails after some files were analyzed fails the run
+	f = newFakeStages()
+	c = &pacedCollector{files: []collector.FileInfo{goFile(root, "app.go", "package main\n")}, hold: 1, analyzed: make(chan struct{}), err: errors.New("walk failed")}
+	status, err = New(c, f, f, f, f).Run(context.Background(), root)
+	if status != storage.RunFailed || err == nil || !strings.Contains(err.Error(), "failed to collect files") {
+		t.Errorf("Expected the walk error to fail the run, got %s: %v", status, err)
+	}
+	if f.rendered != nil {
+		t.Error("Expected a run whose walk failed not to be rendered")
+	}
+}
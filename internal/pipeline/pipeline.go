@@ -0,0 +1,597 @@
+// autodoc/internal/pipeline/pipeline.go
+
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	analyzer "github.com/rgehrsitz/AutoDoc/internal/analysis"
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/internal/progress"
+	"github.com/rgehrsitz/AutoDoc/internal/storage"
+)
+
+// DefaultBatchSize is the number of documents persisted together
+const DefaultBatchSize = 20
+
+// DefaultOrderWindow is the number of collected files ordered by their
+// imports at a time
+const DefaultOrderWindow = 100
+
+// Result is what the stages of the pipeline know about one file
+type Result struct {
+	File       collector.FileInfo
+	Document   *storage.Document    // The page to store, with every field filled
+	Analysis   *analyzer.Analysis   // Structured analysis; nil for free-form analyzers and reused pages
+	Raw        string               // Raw analyzer response
+	References []*storage.Reference // Cross-file references found by the ReferenceResolver
+	Reused     bool                 // The page was loaded from storage instead of analyzed
+	Failure    *StageError          // Why the file could not be documented; Document is then a failure page
+}
+
+// Collector enumerates the files to document under a root directory. It
+// sends each file as it is found, closes the file channel when it is done and
+// then yields its error, if any, on the error channel. Cancelling ctx stops it.
+type Collector interface {
+	Collect(ctx context.Context, root string) (<-chan collector.FileInfo, <-chan error)
+}
+
+// Analyzer documents a single file. When analysis fails, the returned result
+// may still carry the raw response for the failure report.
+type Analyzer interface {
+	Analyze(ctx context.Context, file collector.FileInfo) (*Result, error)
+	// Version identifies the prompts used for file, so stored pages built
+	// with other prompts can be found
+	Version(file collector.FileInfo) string
+}
+
+// ReferenceResolver finds the references of an analyzed file and adds them
+// to its result
+type ReferenceResolver interface {
+	Resolve(ctx context.Context, result *Result) error
+}
+
+// Persister stores analyzed pages and loads the pages of earlier runs
+type Persister interface {
+	// Load returns the stored page for path, or nil if there is none
+	Load(ctx context.Context, path string) (*storage.Document, error)
+	Persist(ctx context.Context, results []*Result) error
+}
+
+// Renderer turns the pages of a run into the documentation site
+type Renderer interface {
+	Render(ctx context.Context, results []*Result) error
+}
+
+// Checkpoint records per-file progress so a run can be resumed.
+// *generator.Checkpoint implements it.
+type Checkpoint interface {
+	ShouldProcess(path string) bool
+	Queue(paths ...string) error
+	Done(path string) error
+	Failed(path string, err error) error
+	Finish(status storage.RunStatus) error
+}
+
+// Pipeline runs the stages of a documentation run in order: collect,
+// analyze, resolve references, persist and render. Any stage can be replaced
+// by a custom implementation; the resolver and renderer may be nil.
+type Pipeline struct {
+	collector       Collector
+	analyzer        Analyzer
+	resolver        ReferenceResolver
+	persister       Persister
+	renderer        Renderer
+	checkpoint      Checkpoint
+	progress        *progress.Tracker
+	ledger          *llm.Ledger
+	outdatedOnly    bool
+	renderPartial   bool
+	continueOnError bool
+	selected        func(path string) bool
+	batchSize       int
+	orderWindow     int
+	workers         int
+	commit          string
+	failures        []*StageError
+}
+
+// New creates a pipeline from its stages
+func New(collector Collector, analyzer Analyzer, resolver ReferenceResolver, persister Persister, renderer Renderer) *Pipeline {
+	return &Pipeline{
+		collector:   collector,
+		analyzer:    analyzer,
+		resolver:    resolver,
+		persister:   persister,
+		renderer:    renderer,
+		batchSize:   DefaultBatchSize,
+		orderWindow: DefaultOrderWindow,
+		workers:     1,
+	}
+}
+
+// SetCheckpoint records per-file progress in checkpoint and reuses the
+// stored pages of files it already marks as done
+func (p *Pipeline) SetCheckpoint(checkpoint Checkpoint) {
+	p.checkpoint = checkpoint
+}
+
+// SetProgress reports the stages and files of the run to tracker
+func (p *Pipeline) SetProgress(tracker *progress.Tracker) {
+	p.progress = tracker
+}
+
+// SetLedger stops scheduling new files once the ledger's budget is spent,
+// and reports its token usage
+func (p *Pipeline) SetLedger(ledger *llm.Ledger) {
+	p.ledger = ledger
+}
+
+// SetOutdatedOnly reuses stored pages built with the current prompt version
+// instead of analyzing their files again
+func (p *Pipeline) SetOutdatedOnly(outdatedOnly bool) {
+	p.outdatedOnly = outdatedOnly
+}
+
+// SetRenderPartial renders the pages documented so far when a run is
+// interrupted; by default interrupted runs are not rendered
+func (p *Pipeline) SetRenderPartial(renderPartial bool) {
+	p.renderPartial = renderPartial
+}
+
+// SetContinueOnError keeps documenting the remaining files when one fails.
+// Failed files are recorded in the checkpoint and rendered as failure pages.
+func (p *Pipeline) SetContinueOnError(continueOnError bool) {
+	p.continueOnError = continueOnError
+}
+
+// SetSelect analyzes only the files for which selected returns true; the
+// stored pages of the other files are reused when they exist
+func (p *Pipeline) SetSelect(selected func(path string) bool) {
+	p.selected = selected
+}
+
+// SetCommit records the SHA of the commit being documented on every page
+// analyzed from now on; reused pages keep the commit they were built from
+func (p *Pipeline) SetCommit(commit string) {
+	p.commit = commit
+}
+
+// Failures returns the failures of the last run
+func (p *Pipeline) Failures() []*StageError {
+	return p.failures
+}
+
+// SetWorkers sets how many files are analyzed at once; the Analyzer must
+// then be safe for concurrent use. Each file still waits for the files it
+// imports, and the other stages are never called concurrently.
+func (p *Pipeline) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	p.workers = workers
+}
+
+// SetBatchSize sets how many analyzed pages are persisted together
+func (p *Pipeline) SetBatchSize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	p.batchSize = size
+}
+
+// SetOrderWindow sets how many collected files are ordered by their imports
+// at a time. Each window is scheduled as soon as it is full, so analysis
+// starts while the walk goes on; a file waits only for the files it imports
+// that were collected in its own or an earlier window. A window of 1
+// analyzes files in collection order.
+func (p *Pipeline) SetOrderWindow(size int) {
+	if size < 1 {
+		size = 1
+	}
+	p.orderWindow = size
+}
+
+// Run documents the files under root and returns the final run status. The
+// error describes why a failed run stopped.
+func (p *Pipeline) Run(ctx context.Context, root string) (storage.RunStatus, error) {
+	status, err := p.run(ctx, root)
+	if p.checkpoint != nil {
+		if cpErr := p.checkpoint.Finish(status); cpErr != nil {
+			log.Printf("Failed to record run status: %v", cpErr)
+		}
+	}
+	p.progress.Finish(string(status))
+	return status, err
+}
+
+// run executes the stages and returns the run status
+func (p *Pipeline) run(ctx context.Context, root string) (storage.RunStatus, error) {
+	p.failures = nil
+
+	// Files are analyzed while they are still being collected; stopping
+	// early cancels the walk
+	p.progress.Stage(StageCollect)
+	collectCtx, stopCollect := context.WithCancel(ctx)
+	defer stopCollect()
+	stream, collectErrs := p.collector.Collect(collectCtx, root)
+
+	var (
+		results, pending []*Result
+		status           = storage.RunCompleted
+		runErr           error
+		unselected       int
+		mu               sync.Mutex // Guards the run state shared with the workers
+		wg               sync.WaitGroup
+	)
+	documented := make(map[string]*storage.Document)
+	graph := make(ImportGraph)
+	index := newImportIndex(root)
+	position := make(map[string]int)
+	var finished []chan struct{}
+	kept := make(map[string]int) // Index of each file's result
+	keep := func(result *Result) {
+		if j, ok := kept[result.File.Path]; ok {
+			results[j] = result
+		} else {
+			kept[result.File.Path] = len(results)
+			results = append(results, result)
+		}
+		if result.Failure == nil {
+			documented[result.File.Path] = result.Document
+		} else {
+			delete(documented, result.File.Path)
+		}
+	}
+	// reject records failure of file in any stage. With continue-on-error the
+	// file is rendered as a failure page, replacing any page kept for it;
+	// otherwise the run fails.
+	reject := func(file collector.FileInfo, failure *StageError) {
+		p.fail(failure)
+		if !p.continueOnError {
+			if status != storage.RunFailed {
+				status = storage.RunFailed
+				runErr = failure
+			}
+			return
+		}
+		page := FailurePage(file, failure)
+		page.Commit = p.commit
+		keep(&Result{File: file, Document: page, Failure: failure})
+	}
+	// flush persists pending pages, rejecting their files if that fails
+	flush := func(ctx context.Context) {
+		if err := p.flush(ctx, pending); err != nil {
+			log.Printf("Failed to save documents: %v", err)
+			for _, result := range pending {
+				reject(result.File, &StageError{Path: result.File.Path, Stage: StagePersist, Err: err})
+			}
+		}
+		pending = nil
+	}
+	stopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return status != storage.RunCompleted
+	}
+	// nextWindow returns the next files to schedule, analyzed after the files
+	// they import, and records them as pending; it returns nil once the
+	// collector is done
+	nextWindow := func() ([]collector.FileInfo, error) {
+		var window []collector.FileInfo
+	collect:
+		for len(window) < p.orderWindow {
+			select {
+			case file, ok := <-stream:
+				if !ok {
+					break collect
+				}
+				window = append(window, file)
+			case <-ctx.Done():
+				return nil, nil
+			}
+		}
+		if len(window) == 0 {
+			return nil, nil
+		}
+		index.addTo(graph, window)
+		window = graph.Order(window)
+
+		paths := make([]string, len(window))
+		for i, file := range window {
+			paths[i] = file.Path
+			position[file.Path] = len(finished)
+			finished = append(finished, make(chan struct{}))
+		}
+		if p.checkpoint != nil {
+			if err := p.checkpoint.Queue(paths...); err != nil {
+				return nil, fmt.Errorf("failed to record run files: %w", err)
+			}
+		}
+		p.progress.Queue(paths...)
+		return window, nil
+	}
+
+	// Analyze each file once the files it imports are finished; pages are
+	// persisted in batches and flushed before returning
+	p.progress.Stage(StageAnalyze)
+	slots := make(chan struct{}, p.workers)
+	// start schedules the analysis of file and reports whether to go on
+	start := func(file collector.FileInfo) bool {
+		i := position[file.Path]
+
+		// The persister is only used under mu
+		mu.Lock()
+		result, ok := p.reuse(ctx, file)
+		if ok {
+			keep(result)
+		}
+		mu.Unlock()
+		if ok {
+			p.progress.Skip(file.Path)
+			p.done(file.Path)
+			close(finished[i])
+			return true
+		}
+
+		// Files left out of the selection keep their stored page, if any
+		if p.selected != nil && !p.selected(file.Path) {
+			mu.Lock()
+			if doc, err := p.persister.Load(ctx, file.Path); err == nil && doc != nil {
+				keep(&Result{File: file, Document: doc, Reused: true})
+			}
+			unselected++
+			mu.Unlock()
+			p.progress.Skip(file.Path)
+			close(finished[i])
+			return true
+		}
+
+		// Stop scheduling new work once the budget is spent
+		if p.ledger != nil {
+			if err := p.ledger.Check(); err != nil {
+				log.Printf("Skipping remaining files: %v", err)
+				mu.Lock()
+				status = storage.RunIncomplete
+				mu.Unlock()
+				return false
+			}
+		}
+
+		// Wait for a free worker
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		// Only the scheduler touches the graph, so the imports and the
+		// channels to wait for are looked up before the worker starts
+		imports := graph[file.Path]
+		var waits []chan struct{}
+		for _, path := range imports {
+			// Imports ordered later belong to the same import cycle
+			if j, ok := position[path]; ok && j < i {
+				waits = append(waits, finished[j])
+			}
+		}
+		done := finished[i]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer close(done)
+
+			if !awaitImports(ctx, waits) {
+				return
+			}
+			mu.Lock()
+			deps := dependencies(root, imports, documented)
+			mu.Unlock()
+
+			p.progress.Start(file.Path)
+			file.Dependencies = deps
+			result, err := p.analyzer.Analyze(ctx, file)
+			if p.ledger != nil {
+				total, cost := p.ledger.Total()
+				p.progress.Usage(total.TotalTokens, cost)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				// A cancelled file stays pending for the next attempt
+				if ctx.Err() != nil {
+					return
+				}
+				failure := &StageError{Path: file.Path, Stage: StageAnalyze, Err: err}
+				if result != nil {
+					failure.Raw = result.Raw
+				}
+				p.progress.Fail(file.Path, err)
+				reject(file, failure)
+				return
+			}
+			result.Document.Commit = p.commit
+
+			if p.resolver != nil {
+				if err := p.resolver.Resolve(ctx, result); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					p.progress.Fail(file.Path, err)
+					reject(file, &StageError{Path: file.Path, Stage: StageResolve, Err: err})
+					return
+				}
+			}
+			keep(result)
+			pending = append(pending, result)
+			if len(pending) >= p.batchSize {
+				flush(ctx)
+			}
+			p.progress.Analyzed(file.Path)
+		}()
+		return true
+	}
+
+schedule:
+	for {
+		window, err := nextWindow()
+		if err != nil {
+			mu.Lock()
+			status, runErr = storage.RunFailed, err
+			mu.Unlock()
+			break
+		}
+		if len(window) == 0 {
+			break
+		}
+		for _, file := range window {
+			if ctx.Err() != nil || stopped() || !start(file) {
+				break schedule
+			}
+		}
+	}
+	wg.Wait()
+
+	// Stop the walk if scheduling ended early. A run still completed went
+	// through the whole walk, so its error is a failure to collect files.
+	stopCollect()
+	for range stream {
+	}
+	if err := <-collectErrs; err != nil && status == storage.RunCompleted && ctx.Err() == nil {
+		status, runErr = storage.RunFailed, fmt.Errorf("failed to collect files: %w", err)
+	}
+
+	if ctx.Err() != nil {
+		status = storage.RunInterrupted
+		runErr = ctx.Err()
+	}
+	flush(context.WithoutCancel(ctx))
+
+	// Workers finish out of order; pages are rendered in collection order
+	sort.SliceStable(results, func(i, j int) bool {
+		return position[results[i].File.Path] < position[results[j].File.Path]
+	})
+
+	// Failed and unselected files are left for a later attempt
+	if status == storage.RunCompleted && (len(p.failures) > 0 || unselected > 0) {
+		status = storage.RunIncomplete
+	}
+
+	// Render the site, from partial data only when asked to
+	render := status == storage.RunCompleted || status == storage.RunIncomplete ||
+		(status == storage.RunInterrupted && p.renderPartial)
+	if render && p.renderer != nil {
+		p.progress.Stage(StageRender)
+		if err := p.renderer.Render(context.WithoutCancel(ctx), results); err != nil {
+			return storage.RunFailed, fmt.Errorf("failed to render documentation: %w", err)
+		}
+	}
+
+	return status, runErr
+}
+
+// awaitImports blocks until every channel in waits, one per imported file,
+// is closed. It returns false if ctx is cancelled first.
+func awaitImports(ctx context.Context, waits []chan struct{}) bool {
+	for _, done := range waits {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// dependencies returns the imported files documented so far with their
+// purposes, named relative to root
+func dependencies(root string, imports []string, documented map[string]*storage.Document) []collector.Dependency {
+	var deps []collector.Dependency
+	for _, path := range imports {
+		doc := documented[path]
+		if doc == nil || doc.Purpose == "" {
+			continue
+		}
+		name := path
+		if rel, err := filepath.Rel(root, path); err == nil {
+			name = filepath.ToSlash(rel)
+		}
+		deps = append(deps, collector.Dependency{Path: name, Purpose: doc.Purpose})
+	}
+	return deps
+}
+
+// reuse returns the stored page of file when it was finished by an earlier
+// attempt of the run, or when only outdated pages are rebuilt and it was
+// built with the current prompts
+func (p *Pipeline) reuse(ctx context.Context, file collector.FileInfo) (*Result, bool) {
+	finished := p.checkpoint != nil && !p.checkpoint.ShouldProcess(file.Path)
+	if !finished && !p.outdatedOnly {
+		return nil, false
+	}
+
+	doc, err := p.persister.Load(ctx, file.Path)
+	if err != nil {
+		log.Printf("Failed to load stored page for %s: %v", file.Path, err)
+		return nil, false
+	}
+	if doc == nil || (!finished && doc.PromptVersion != p.analyzer.Version(file)) {
+		return nil, false
+	}
+	return &Result{File: file, Document: doc, Reused: true}, true
+}
+
+// flush persists results and marks their files done. Files whose pages
+// could not be stored are left to the caller.
+func (p *Pipeline) flush(ctx context.Context, results []*Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	if err := p.persister.Persist(ctx, results); err != nil {
+		return err
+	}
+	for _, result := range results {
+		p.done(result.File.Path)
+	}
+	return nil
+}
+
+// fail records failure in the checkpoint and the run's failures
+func (p *Pipeline) fail(failure *StageError) {
+	p.failures = append(p.failures, failure)
+	if p.checkpoint == nil {
+		return
+	}
+	if err := p.checkpoint.Failed(failure.Path, failure); err != nil {
+		log.Printf("Failed to checkpoint %s: %v", failure.Path, err)
+	}
+}
+
+// done marks path as finished in the checkpoint
+func (p *Pipeline) done(path string) {
+	if p.checkpoint == nil {
+		return
+	}
+	if err := p.checkpoint.Done(path); err != nil {
+		log.Printf("Failed to checkpoint %s: %v", path, err)
+	}
+}
+
+// DocumentID returns the identifier of the page for path
+func DocumentID(path string) string {
+	hash := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(hash[:])[:16] // Uses first 16 characters of the hash
+}
@@ -0,0 +1,514 @@
+// autodoc/internal/pipeline/pipeline_test.go
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	analyzer "github.com/rgehrsitz/AutoDoc/internal/analysis"
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/generator"
+	"github.com/rgehrsitz/AutoDoc/internal/storage"
+)
+
+// fakeStages implements every stage in memory and records the calls
+type fakeStages struct {
+	files    []collector.FileInfo
+	stored   map[string]*storage.Document
+	batches  int
+	analyzed []string
+	rendered []*Result
+	fail     string // Path whose analysis fails
+	unknown  string // Path whose references cannot be resolved
+	readOnly bool   // Persisting fails
+}
+
+func (f *fakeStages) Collect(ctx context.Context, root string) (<-chan collector.FileInfo, <-chan error) {
+	files := make(chan collector.FileInfo, len(f.files))
+	errs := make(chan error, 1)
+	for _, file := range f.files {
+		files <- file
+	}
+	close(files)
+	close(errs)
+	return files, errs
+}
+
+func (f *fakeStages) Version(file collector.FileInfo) string { return "v2" }
+
+func (f *fakeStages) Analyze(ctx context.Context, file collector.FileInfo) (*Result, error) {
+	if file.Path == f.fail {
+		return &Result{File: file, Raw: "not json"}, errors.New("model refused")
+	}
+	f.analyzed = append(f.analyzed, file.Path)
+	return &Result{File: file, Document: NewDocument(file, "doc of "+file.Path, "v2")}, nil
+}
+
+func (f *fakeStages) Resolve(ctx context.Context, result *Result) error {
+	if result.File.Path == f.unknown {
+		return errors.New("reference index unavailable")
+	}
+	result.References = []*storage.Reference{{SourceID: result.Document.ID, TargetID: "dep", Type: "imports"}}
+	return nil
+}
+
+func (f *fakeStages) Load(ctx context.Context, path string) (*storage.Document, error) {
+	return f.stored[path], nil
+}
+
+func (f *fakeStages) Persist(ctx context.Context, results []*Result) error {
+	f.batches++
+	if f.readOnly {
+		return errors.New("database is read-only")
+	}
+	for _, result := range results {
+		if len(result.References) != 1 {
+			return errors.New("references were not resolved before persisting")
+		}
+		f.stored[result.File.Path] = result.Document
+	}
+	return nil
+}
+
+func (f *fakeStages) Render(ctx context.Context, results []*Result) error {
+	f.rendered = results
+	return nil
+}
+
+func newFakeStages(paths ...string) *fakeStages {
+	f := &fakeStages{stored: make(map[string]*storage.Document)}
+	for _, path := range paths {
+		f.files = append(f.files, collector.FileInfo{Path: path, Language: "go", Type: "source"})
+	}
+	return f
+}
+
+func (f *fakeStages) pipeline() *Pipeline {
+	return New(f, f, f, f, f)
+}
+
+func TestPipelineRunsStagesInOrder(t *testing.T) {
+	f := newFakeStages("a.go", "b.go", "c.go", "d.go", "e.go")
+	f.stored["b.go"] = &storage.Document{ID: DocumentID("b.go"), Path: "b.go", PromptVersion: "v2"}
+	f.stored["c.go"] = &storage.Document{ID: DocumentID("c.go"), Path: "c.go", PromptVersion: "v1"}
+
+	p := f.pipeline()
+	p.SetOutdatedOnly(true)
+	p.SetBatchSize(2)
+	status, err := p.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Failed to run pipeline: %v", err)
+	}
+	if status != storage.RunCompleted {
+		t.Errorf("Expected status %s, got %s", storage.RunCompleted, status)
+	}
+
+	// b.go is current and reused; c.go was built with older prompts
+	if strings.Join(f.analyzed, ",") != "a.go,c.go,d.go,e.go" {
+		t.Errorf("Expected a, c, d and e to be analyzed, got %v", f.analyzed)
+	}
+	if f.batches != 2 {
+		t.Errorf("Expected 2 batches, got %d", f.batches)
+	}
+	if len(f.rendered) != 5 {
+		t.Fatalf("Expected 5 rendered pages, got %d", len(f.rendered))
+	}
+	if !f.rendered[1].Reused {
+		t.Error("Expected b.go to be rendered from its stored page")
+	}
+}
+
+func TestPipelineStopsOnFailure(t *testing.T) {
+	f := newFakeStages("a.go", "b.go", "c.go")
+	f.fail = "b.go"
+
+	status, err := f.pipeline().Run(context.Background(), "root")
+	if status != storage.RunFailed {
+		t.Errorf("Expected status %s, got %s", storage.RunFailed, status)
+	}
+	if err == nil || !strings.Contains(err.Error(), "b.go") {
+		t.Errorf("Expected an error naming b.go, got %v", err)
+	}
+	// Pages analyzed before the failure are still stored, but not rendered
+	if f.stored["a.go"] == nil {
+		t.Error("Expected a.go to be stored")
+	}
+	if f.rendered != nil {
+		t.Error("Expected a failed run not to be rendered")
+	}
+}
+
+func TestPipelineContinuesOnError(t *testing.T) {
+	store, err := storage.NewBadgerStorage(filepath.Join(t.TempDir(), "db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	checkpoint, err := generator.StartRun(store, "root", "")
+	if err != nil {
+		t.Fatalf("Failed to start run: %v", err)
+	}
+
+	f := newFakeStages("a.go", "b.go", "c.go")
+	f.fail = "b.go"
+	p := f.pipeline()
+	p.SetCheckpoint(checkpoint)
+	p.SetContinueOnError(true)
+	status, err := p.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Failed to run pipeline: %v", err)
+	}
+	if status != storage.RunIncomplete {
+		t.Errorf("Expected status %s, got %s", storage.RunIncomplete, status)
+	}
+	if strings.Join(f.analyzed, ",") != "a.go,c.go" {
+		t.Errorf("Expected a and c to be analyzed, got %v", f.analyzed)
+	}
+
+	// The failed file is rendered as a failure page but not stored
+	if len(f.rendered) != 3 || f.rendered[1].Failure == nil {
+		t.Fatalf("Expected b.go to be rendered as a failure page, got %v", f.rendered)
+	}
+	if !strings.Contains(f.rendered[1].Document.Content, "model refused") {
+		t.Errorf("Expected the failure page to show the error, got %q", f.rendered[1].Document.Content)
+	}
+	if f.stored["b.go"] != nil {
+		t.Error("Expected the failure page not to be stored")
+	}
+
+	failures := checkpoint.Failures()
+	if len(failures) != 1 {
+		t.Fatalf("Expected 1 failure, got %d", len(failures))
+	}
+	if failures[0].Path != "b.go" || failures[0].Stage != StageAnalyze || failures[0].RawResponse != "not json" {
+		t.Errorf("Expected b.go to fail in the analyze stage with its raw response, got %+v", failures[0])
+	}
+
+	// Retrying analyzes only the failed file and reuses the other pages
+	checkpoint, err = generator.ResumeRun(store, checkpoint.ID(), "")
+	if err != nil {
+		t.Fatalf("Failed to resume run: %v", err)
+	}
+	f.fail = ""
+	f.analyzed = nil
+	p = f.pipeline()
+	p.SetCheckpoint(checkpoint)
+	p.SetSelect(checkpoint.IsFailed)
+	p.SetContinueOnError(true)
+	status, err = p.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Failed to retry failed files: %v", err)
+	}
+	if status != storage.RunCompleted {
+		t.Errorf("Expected status %s, got %s", storage.RunCompleted, status)
+	}
+	if strings.Join(f.analyzed, ",") != "b.go" {
+		t.Errorf("Expected only b.go to be analyzed, got %v", f.analyzed)
+	}
+	if len(checkpoint.Failures()) != 0 {
+		t.Errorf("Expected no failures after the retry, got %v", checkpoint.Failures())
+	}
+}
+
+func TestPipelineRecordsResolveAndPersistFailures(t *testing.T) {
+	f := newFakeStages("a.go", "b.go", "c.go")
+	f.unknown = "b.go"
+	p := f.pipeline()
+	p.SetContinueOnError(true)
+	status, err := p.Run(context.Background(), "root")
+	if err != nil || status != storage.RunIncomplete {
+		t.Fatalf("Expected an incomplete run, got %s: %v", status, err)
+	}
+	failures := p.Failures()
+	if len(failures) != 1 || failures[0].Path != "b.go" || failures[0].Stage != StageResolve {
+		t.Fatalf("Expected b.go to fail in the resolve stage, got %v", failures)
+	}
+	if f.stored["b.go"] != nil || f.rendered[1].Failure == nil {
+		t.Error("Expected b.go to be rendered as a failure page and not stored")
+	}
+
+	// Without continue-on-error a persist failure fails the run
+	f = newFakeStages("a.go", "b.go")
+	f.readOnly = true
+	p = f.pipeline()
+	status, err = p.Run(context.Background(), "root")
+	if status != storage.RunFailed || err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Fatalf("Expected the run to fail on the persist error, got %s: %v", status, err)
+	}
+	if f.rendered != nil {
+		t.Error("Expected a failed run not to be rendered")
+	}
+
+	// With it, the unsaved pages are rendered as failure pages
+	f = newFakeStages("a.go", "b.go")
+	f.readOnly = true
+	p = f.pipeline()
+	p.SetContinueOnError(true)
+	status, _ = p.Run(context.Background(), "root")
+	if status != storage.RunIncomplete {
+		t.Errorf("Expected status %s, got %s", storage.RunIncomplete, status)
+	}
+	if len(f.rendered) != 2 {
+		t.Fatalf("Expected 2 rendered pages, got %d", len(f.rendered))
+	}
+	for _, result := range f.rendered {
+		if result.Failure == nil || result.Failure.Stage != StagePersist {
+			t.Errorf("Expected %s to be rendered as a persist failure, got %+v", result.File.Path, result.Failure)
+		}
+	}
+}
+
+func TestFailurePageFencesRawResponse(t *testing.T) {
+	raw := "Here you go:\n```json\n{\"purpose\": \"x\"}\n```\nand ```` too"
+	page := FailurePage(collector.FileInfo{Path: "a.go"}, &StageError{Path: "a.go", Stage: StageAnalyze, Err: errors.New("invalid"), Raw: raw})
+	if !strings.Contains(page.Content, "\n`````\n"+raw+"\n`````\n") {
+		t.Errorf("Expected the raw response inside a five-backtick fence, got %q", page.Content)
+	}
+}
+
+func TestFailurePageTruncatesRawResponse(t *testing.T) {
+	// A three-byte rune straddles the limit
+	raw := strings.Repeat("a", maxRawInPage-1) + "€" + strings.Repeat("b", 10)
+	page := FailurePage(collector.FileInfo{Path: "a.go"}, &StageError{Path: "a.go", Stage: StageAnalyze, Err: errors.New("invalid"), Raw: raw})
+	if !utf8.ValidString(page.Content) {
+		t.Errorf("Expected the page to be valid UTF-8, got %q", page.Content[len(page.Content)-40:])
+	}
+	if !strings.Contains(page.Content, strings.Repeat("a", maxRawInPage-1)+"\n...") {
+		t.Errorf("Expected the raw response cut before the rune, got %q", page.Content[len(page.Content)-40:])
+	}
+}
+
+func TestPipelineInterrupted(t *testing.T) {
+	f := newFakeStages("a.go", "b.go")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := f.pipeline()
+	status, _ := p.Run(ctx, "root")
+	if status != storage.RunInterrupted {
+		t.Errorf("Expected status %s, got %s", storage.RunInterrupted, status)
+	}
+	if f.rendered != nil {
+		t.Error("Expected an interrupted run not to be rendered")
+	}
+}
+
+// gatedAnalyzer wraps fakeStages, tracking concurrent analyses and
+// cancelling the run once cancelAfter files have been analyzed
+type gatedAnalyzer struct {
+	*fakeStages
+	mu          sync.Mutex
+	inFlight    int
+	maxSeen     int
+	cancelAfter int
+	cancel      context.CancelFunc
+}
+
+func (g *gatedAnalyzer) Analyze(ctx context.Context, file collector.FileInfo) (*Result, error) {
+	g.mu.Lock()
+	g.inFlight++
+	g.maxSeen = max(g.maxSeen, g.inFlight)
+	stop := g.cancel != nil && len(g.analyzed) >= g.cancelAfter
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		g.inFlight--
+		g.mu.Unlock()
+	}()
+
+	if stop {
+		g.cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	time.Sleep(time.Millisecond)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.fakeStages.Analyze(ctx, file)
+}
+
+func TestPipelineWorkers(t *testing.T) {
+	var paths []string
+	for i := 0; i < 40; i++ {
+		paths = append(paths, fmt.Sprintf("f%02d.go", i))
+	}
+	f := newFakeStages(paths...)
+	f.fail = "f07.go"
+	g := &gatedAnalyzer{fakeStages: f}
+	p := New(f, g, f, f, f)
+	p.SetWorkers(3)
+	p.SetContinueOnError(true)
+
+	status, err := p.Run(context.Background(), "root")
+	if err != nil {
+		t.Fatalf("Failed to run pipeline: %v", err)
+	}
+	if status != storage.RunIncomplete {
+		t.Errorf("Expected status %s, got %s", storage.RunIncomplete, status)
+	}
+	if g.maxSeen > 3 {
+		t.Errorf("Expected at most 3 concurrent analyses, got %d", g.maxSeen)
+	}
+	if len(f.stored) != 39 || len(p.Failures()) != 1 {
+		t.Errorf("Expected 39 stored pages and 1 failure, got %d and %d", len(f.stored), len(p.Failures()))
+	}
+	for i, result := range f.rendered {
+		if result.File.Path != paths[i] {
+			t.Fatalf("Expected pages rendered in collection order, got %s at %d", result.File.Path, i)
+		}
+	}
+}
+
+func TestPipelineResumesInterruptedRun(t *testing.T) {
+	store, err := storage.NewBadgerStorage(filepath.Join(t.TempDir(), "db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	var paths []string
+	for i := 0; i < 10; i++ {
+		paths = append(paths, filepath.Join("/src", fmt.Sprintf("f%d.go", i)))
+	}
+	checkpoint, err := generator.StartRun(store, "/src", "/src")
+	if err != nil {
+		t.Fatalf("Failed to start run: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newFakeStages(paths...)
+	g := &gatedAnalyzer{fakeStages: f, cancelAfter: 3, cancel: cancel}
+	p := New(f, g, f, f, f)
+	p.SetCheckpoint(checkpoint)
+	status, err := p.Run(ctx, "/src")
+	if status != storage.RunInterrupted || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected an interrupted run, got %s: %v", status, err)
+	}
+
+	// Cancelled files are left pending rather than recorded as failures
+	counts := checkpoint.Counts()
+	if counts[storage.FileDone] != 3 || counts[storage.FileFailed] != 0 {
+		t.Fatalf("Expected 3 done and no failed files, got %v", counts)
+	}
+
+	// Resuming from a differently spelled root analyzes only the rest
+	resumed, err := generator.ResumeRun(store, checkpoint.ID(), "/src/")
+	if err != nil {
+		t.Fatalf("Failed to resume run: %v", err)
+	}
+	f.analyzed = nil
+	p = f.pipeline()
+	p.SetCheckpoint(resumed)
+	status, err = p.Run(context.Background(), "/src")
+	if err != nil || status != storage.RunCompleted {
+		t.Fatalf("Expected the resumed run to complete, got %s: %v", status, err)
+	}
+	if len(f.analyzed) != 7 {
+		t.Errorf("Expected the 7 remaining files to be analyzed, got %v", f.analyzed)
+	}
+	if counts := resumed.Counts(); counts[storage.FileDone] != 10 {
+		t.Errorf("Expected 10 done files, got %v", counts)
+	}
+	saved, err := store.GetRun(checkpoint.ID())
+	if err != nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	if saved.Status != storage.RunCompleted {
+		t.Errorf("Expected run status %s, got %s", storage.RunCompleted, saved.Status)
+	}
+}
+
+func TestStructuredAnalyzerFillsDocument(t *testing.T) {
+	dir := t.TempDir()
+	src := "package calc\n\n// Add adds two numbers.\nfunc Add(a, b int) int { return a + b }\n"
+	if err := os.WriteFile(filepath.Join(dir, "calc.go"), []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	store, err := storage.NewBadgerStorage(filepath.Join(t.TempDir(), "db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	p := New(
+		NewCollector(collector.NewCollector(), []string{"go"}),
+		NewStructuredAnalyzer(analyzer.NewStaticAnalyzer()),
+		NewReferenceResolver(store),
+		NewPersister(store),
+		nil,
+	)
+	p.SetCommit("0123abcd")
+	if _, err := p.Run(context.Background(), dir); err != nil {
+		t.Fatalf("Failed to run pipeline: %v", err)
+	}
+
+	doc, err := store.GetDocument(DocumentID(filepath.Join(dir, "calc.go")))
+	if err != nil {
+		t.Fatalf("Failed to get document: %v", err)
+	}
+	if doc.Purpose == "" || len(doc.Components) == 0 {
+		t.Errorf("Expected purpose and components to be stored, got %+v", doc)
+	}
+	if !strings.Contains(doc.Content, "## Purpose") {
+		t.Errorf("Expected markdown content, got %q", doc.Content)
+	}
+	if doc.PromptVersion != analyzer.StaticVersion {
+		t.Errorf("Expected prompt version %s, got %s", analyzer.StaticVersion, doc.PromptVersion)
+	}
+	if doc.Commit != "0123abcd" {
+		t.Errorf("Expected commit 0123abcd, got %q", doc.Commit)
+	}
+}
+
+func TestCollectorSkipsFilteredFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"calc.go":      "package calc\n",
+		"calc.pb.go":   "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage calc\n",
+		"calc_test.go": "package calc\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	c := NewCollector(collector.NewCollector(), []string{"go"})
+	c.SetFilter(collector.NewFilter(nil, []string{"*_test.go"}))
+	stream, errs := c.Collect(context.Background(), dir)
+	var collected []collector.FileInfo
+	for file := range stream {
+		collected = append(collected, file)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Failed to collect files: %v", err)
+	}
+	if len(collected) != 1 || filepath.Base(collected[0].Path) != "calc.go" {
+		t.Errorf("Expected only calc.go to be collected, got %v", collected)
+	}
+
+	reasons := make(map[string]string)
+	for _, skip := range c.Skipped() {
+		reasons[filepath.Base(skip.Path)] = skip.Reason
+	}
+	if reasons["calc.pb.go"] != collector.SkipGenerated || reasons["calc_test.go"] != collector.SkipExcluded {
+		t.Errorf("Expected generated and excluded files to be skipped, got %v", reasons)
+	}
+
+	var report strings.Builder
+	WriteSkipReport(&report, c.Skipped())
+	if !strings.Contains(report.String(), "Skipped files (2)") || !strings.Contains(report.String(), collector.SkipGenerated) {
+		t.Errorf("Expected the skip report to list both files, got %q", report.String())
+	}
+}
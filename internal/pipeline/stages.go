@@ -0,0 +1,290 @@
+// autodoc/internal/pipeline/stages.go
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	analyzer "github.com/rgehrsitz/AutoDoc/internal/analysis"
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/docs"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/internal/storage"
+)
+
+// FSCollector collects files through a collector.Collector
+type FSCollector struct {
+	collector collector.Collector
+	match     collector.Matcher
+	filter    *collector.Filter
+	skipped   []collector.Skipped
+}
+
+// NewCollector creates a Collector for the given file extensions and the
+// well-known files of their languages. Without extensions it collects the
+// files the collector.Languages registry recognizes.
+func NewCollector(c collector.Collector, extensions []string) *FSCollector {
+	return &FSCollector{collector: c, match: collector.Languages.Matcher(extensions)}
+}
+
+// SetFilter skips the matching files the filter rejects
+func (c *FSCollector) SetFilter(filter *collector.Filter) {
+	c.filter = filter
+}
+
+// Collect streams the matching files under root, without their content, as
+// the walk finds them; analyzers load the content one file at a time. The
+// file channel is closed when the walk ends and the error channel then
+// yields the walk error, if any. Cancelling ctx stops the walk.
+func (c *FSCollector) Collect(ctx context.Context, root string) (<-chan collector.FileInfo, <-chan error) {
+	c.skipped = nil
+	files := make(chan collector.FileInfo)
+	errs := make(chan error, 1)
+	stream, walkErrs := c.collector.Stream(ctx, root, c.match)
+
+	go func() {
+		defer close(errs)
+		defer close(files)
+		for file := range stream {
+			if reason := c.filter.Check(root, file); reason != "" {
+				c.skipped = append(c.skipped, collector.Skipped{Path: file.Path, Reason: reason})
+				continue
+			}
+			select {
+			case files <- file:
+			case <-ctx.Done():
+			}
+		}
+		if err := <-walkErrs; err != nil {
+			errs <- err
+		}
+	}()
+	return files, errs
+}
+
+// Skipped returns the files the filter rejected during the last Collect,
+// once its file channel is closed
+func (c *FSCollector) Skipped() []collector.Skipped {
+	return c.skipped
+}
+
+// WriteSkipReport writes one line per skipped file: its path and the reason
+func WriteSkipReport(w io.Writer, skipped []collector.Skipped) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Skipped files (%d):\n", len(skipped))
+	for _, skip := range skipped {
+		fmt.Fprintf(w, "  %s: %s\n", skip.Path, skip.Reason)
+	}
+}
+
+// StructuredAnalyzer documents files with an analyzer.FileAnalyzer, storing
+// the structured analysis and rendering it as markdown
+type StructuredAnalyzer struct {
+	analyzer analyzer.FileAnalyzer
+}
+
+// NewStructuredAnalyzer creates an Analyzer backed by a FileAnalyzer
+func NewStructuredAnalyzer(a analyzer.FileAnalyzer) *StructuredAnalyzer {
+	return &StructuredAnalyzer{analyzer: a}
+}
+
+// Version returns the prompt version used for file
+func (a *StructuredAnalyzer) Version(file collector.FileInfo) string {
+	return a.analyzer.PromptVersion(file.Language, file.Type)
+}
+
+// Analyze analyzes file and builds its page. The content is loaded for the
+// analysis only; the result keeps the file without it.
+func (a *StructuredAnalyzer) Analyze(ctx context.Context, file collector.FileInfo) (*Result, error) {
+	loaded, err := file.Load()
+	if err != nil {
+		return nil, err
+	}
+	analysis, raw, err := a.analyzer.AnalyzeFile(llm.WithFile(ctx, file.Path), loaded)
+	if err != nil {
+		return &Result{File: file, Raw: raw}, err
+	}
+	doc := NewDocument(file, analyzer.FormatAnalysisMarkdown(analysis), a.Version(file))
+	FillAnalysis(doc, analysis)
+	return &Result{File: file, Document: doc, Analysis: analysis, Raw: raw}, nil
+}
+
+// SourceAnalyzer documents files as free-form markdown with an
+// analyzer.SourceAnalyzer
+type SourceAnalyzer struct {
+	analyzer analyzer.SourceAnalyzer
+}
+
+// NewSourceAnalyzer creates an Analyzer backed by a SourceAnalyzer
+func NewSourceAnalyzer(a analyzer.SourceAnalyzer) *SourceAnalyzer {
+	return &SourceAnalyzer{analyzer: a}
+}
+
+// Version returns the prompt version used for file
+func (a *SourceAnalyzer) Version(file collector.FileInfo) string {
+	return a.analyzer.PromptVersion(sourceLanguage(file), "source")
+}
+
+// Analyze documents file and builds its page
+func (a *SourceAnalyzer) Analyze(ctx context.Context, file collector.FileInfo) (*Result, error) {
+	loaded, err := file.Load()
+	if err != nil {
+		return nil, err
+	}
+	content, err := a.analyzer.AnalyzeSource(llm.WithFile(ctx, file.Path), loaded.Content, sourceLanguage(file))
+	if err != nil {
+		return nil, err
+	}
+	return &Result{File: file, Document: NewDocument(file, content, a.Version(file)), Raw: content}, nil
+}
+
+// sourceLanguage returns the registry language of file, or its extension
+// for files the registry does not know
+func sourceLanguage(file collector.FileInfo) string {
+	if file.Language != "" {
+		return file.Language
+	}
+	return strings.TrimPrefix(filepath.Ext(file.Path), ".")
+}
+
+// NewDocument creates the page for file with the given content
+func NewDocument(file collector.FileInfo, content, promptVersion string) *storage.Document {
+	now := time.Now()
+	return &storage.Document{
+		ID:            DocumentID(file.Path),
+		Path:          file.Path,
+		Type:          storage.TypeModule,
+		Content:       content,
+		References:    []string{},
+		PromptVersion: promptVersion,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// FillAnalysis copies the purpose, components, relations and insights of
+// analysis into doc
+func FillAnalysis(doc *storage.Document, analysis *analyzer.Analysis) {
+	doc.Purpose = analysis.Purpose
+	doc.Insights = analysis.Insights
+
+	doc.Components = make([]storage.ComponentInfo, len(analysis.Components))
+	for i, comp := range analysis.Components {
+		doc.Components[i] = storage.ComponentInfo{
+			Name:            comp.Name,
+			Type:            comp.Type,
+			Description:     comp.Description,
+			Visibility:      comp.Visibility,
+			Dependencies:    comp.Dependencies,
+			NotableFeatures: comp.NotableFeatures,
+			StartLine:       comp.StartLine,
+			EndLine:         comp.EndLine,
+		}
+	}
+
+	doc.Relations = make([]storage.RelationInfo, len(analysis.Relations))
+	for i, rel := range analysis.Relations {
+		doc.Relations[i] = storage.RelationInfo{
+			From: rel.From,
+			To:   rel.To,
+			Type: rel.Type,
+		}
+	}
+}
+
+// AnalysisResolver resolves references from structured analyses with an
+// analyzer.ReferenceProcessor
+type AnalysisResolver struct {
+	processor *analyzer.ReferenceProcessor
+}
+
+// NewReferenceResolver creates a ReferenceResolver that looks up related
+// pages in store
+func NewReferenceResolver(store storage.Storage) *AnalysisResolver {
+	return &AnalysisResolver{processor: analyzer.NewReferenceProcessor(store)}
+}
+
+// Resolve adds the references of result's analysis to it; results without
+// a structured analysis have none
+func (r *AnalysisResolver) Resolve(ctx context.Context, result *Result) error {
+	if result.Analysis == nil {
+		return nil
+	}
+	refs, err := r.processor.ResolveReferences(result.Document, result.Analysis)
+	if err != nil {
+		return err
+	}
+	result.References = refs
+	for _, ref := range refs {
+		result.Document.References = append(result.Document.References, ref.TargetID)
+	}
+	return nil
+}
+
+// StorePersister stores pages and their references in a storage.Storage
+type StorePersister struct {
+	store storage.Storage
+}
+
+// NewPersister creates a Persister backed by store
+func NewPersister(store storage.Storage) *StorePersister {
+	return &StorePersister{store: store}
+}
+
+// Load returns the stored page for path, or nil if there is none
+func (p *StorePersister) Load(ctx context.Context, path string) (*storage.Document, error) {
+	doc, err := p.store.GetDocument(DocumentID(path))
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil || doc.ID == "" {
+		return nil, nil
+	}
+	return doc, nil
+}
+
+// Persist saves the pages of results and their references in batches
+func (p *StorePersister) Persist(ctx context.Context, results []*Result) error {
+	docs := make([]*storage.Document, 0, len(results))
+	var refs []*storage.Reference
+	for _, result := range results {
+		docs = append(docs, result.Document)
+		refs = append(refs, result.References...)
+	}
+
+	if err := p.store.BatchSaveDocuments(docs); err != nil {
+		return fmt.Errorf("failed to save documents: %w", err)
+	}
+	if err := p.store.BatchSaveReferences(refs); err != nil {
+		return fmt.Errorf("failed to save references: %w", err)
+	}
+	return nil
+}
+
+// DocsRenderer renders pages as a static site with docs.GenerateDocumentation
+type DocsRenderer struct {
+	outputDir string
+}
+
+// NewDocsRenderer creates a Renderer writing the site for outputDir
+func NewDocsRenderer(outputDir string) *DocsRenderer {
+	return &DocsRenderer{outputDir: outputDir}
+}
+
+// Render generates the documentation site from the pages of results
+func (r *DocsRenderer) Render(ctx context.Context, results []*Result) error {
+	pages := make(map[string]string, len(results))
+	references := make(map[string][]string, len(results))
+	for _, result := range results {
+		pages[result.File.Path] = result.Document.Content
+		references[result.File.Path] = result.Document.References
+	}
+	return docs.GenerateDocumentation(r.outputDir, pages, references)
+}
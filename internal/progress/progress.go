@@ -0,0 +1,212 @@
+// autodoc/internal/progress/progress.go
+
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies what happened during a run
+type EventType string
+
+const (
+	EventStage    EventType = "stage"    // The run entered a new stage
+	EventQueued   EventType = "queued"   // A file was queued for analysis
+	EventStarted  EventType = "started"  // Analysis of a file started
+	EventAnalyzed EventType = "analyzed" // A file was analyzed successfully
+	EventSkipped  EventType = "skipped"  // A file did not need analysis
+	EventFailed   EventType = "failed"   // Analysis of a file failed
+	EventTokens   EventType = "tokens"   // LLM token usage changed
+	EventWrote    EventType = "wrote"    // A page or asset of a generated site was written
+	EventFinished EventType = "finished" // The run ended
+)
+
+// Event describes one step of a run together with the run totals at that point
+type Event struct {
+	Type      EventType `json:"type"`
+	Time      time.Time `json:"time"`
+	Stage     string    `json:"stage,omitempty"`
+	File      string    `json:"file,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Status    string    `json:"status,omitempty"` // Final run status, for finished events
+	Queued    int       `json:"queued"`           // Files queued so far
+	Completed int       `json:"completed"`        // Files analyzed, skipped or failed
+	Failed    int       `json:"failed"`
+	Tokens    int64     `json:"tokens"` // LLM tokens used so far
+	Cost      float64   `json:"cost"`   // LLM spend so far, in US dollars
+	ETA       float64   `json:"eta_seconds,omitempty"`
+}
+
+// Remaining returns the estimated time until all queued files are completed
+func (e Event) Remaining() time.Duration {
+	return time.Duration(e.ETA * float64(time.Second))
+}
+
+// Reporter receives the events of a run
+type Reporter interface {
+	Report(Event)
+}
+
+// ReporterFunc adapts a function to the Reporter interface
+type ReporterFunc func(Event)
+
+// Report calls f(e)
+func (f ReporterFunc) Report(e Event) {
+	f(e)
+}
+
+// Tracker keeps the totals of a run and reports every change to a Reporter.
+// It is safe for concurrent use, and a nil Tracker reports nothing.
+type Tracker struct {
+	reporter Reporter
+	mu       sync.Mutex
+	now      func() time.Time
+	stage    string
+	started  time.Time // When the first file analysis started
+	queued   int
+	done     int // Files analyzed or failed, used for the ETA
+	skipped  int
+	failed   int
+	tokens   int64
+	cost     float64
+}
+
+// NewTracker creates a tracker that reports to reporter
+func NewTracker(reporter Reporter) *Tracker {
+	return &Tracker{
+		reporter: reporter,
+		now:      time.Now,
+	}
+}
+
+// Stage records that the run entered the named stage, e.g. "collect" or "render"
+func (t *Tracker) Stage(name string) {
+	t.emit(func() Event {
+		t.stage = name
+		return Event{Type: EventStage}
+	})
+}
+
+// Queue records files queued for analysis
+func (t *Tracker) Queue(files ...string) {
+	for _, file := range files {
+		t.emit(func() Event {
+			t.queued++
+			return Event{Type: EventQueued, File: file}
+		})
+	}
+}
+
+// Start records that analysis of file started
+func (t *Tracker) Start(file string) {
+	t.emit(func() Event {
+		if t.started.IsZero() {
+			t.started = t.now()
+		}
+		return Event{Type: EventStarted, File: file}
+	})
+}
+
+// Analyzed records that file was analyzed successfully
+func (t *Tracker) Analyzed(file string) {
+	t.emit(func() Event {
+		t.done++
+		return Event{Type: EventAnalyzed, File: file}
+	})
+}
+
+// Skip records that file did not need analysis, e.g. because it is up to date
+func (t *Tracker) Skip(file string) {
+	t.emit(func() Event {
+		t.skipped++
+		return Event{Type: EventSkipped, File: file}
+	})
+}
+
+// Fail records that analysis of file failed with err
+func (t *Tracker) Fail(file string, err error) {
+	t.emit(func() Event {
+		t.done++
+		t.failed++
+		e := Event{Type: EventFailed, File: file}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		return e
+	})
+}
+
+// Usage records the LLM tokens and cost used by the run so far
+func (t *Tracker) Usage(tokens int64, cost float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	changed := tokens != t.tokens || cost != t.cost
+	t.mu.Unlock()
+	if !changed {
+		return
+	}
+	t.emit(func() Event {
+		t.tokens, t.cost = tokens, cost
+		return Event{Type: EventTokens}
+	})
+}
+
+// Wrote records that the page or asset at path was written
+func (t *Tracker) Wrote(path string) {
+	t.emit(func() Event {
+		return Event{Type: EventWrote, File: path}
+	})
+}
+
+// Finish records that the run ended with the given status
+func (t *Tracker) Finish(status string) {
+	t.emit(func() Event {
+		return Event{Type: EventFinished, Status: status}
+	})
+}
+
+// Snapshot returns the current run totals
+func (t *Tracker) Snapshot() Event {
+	if t == nil {
+		return Event{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fill(Event{})
+}
+
+// emit applies update under the lock and reports the resulting event.
+// Events are reported in the order their updates were applied.
+func (t *Tracker) emit(update func() Event) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e := t.fill(update())
+	if t.reporter != nil {
+		t.reporter.Report(e)
+	}
+}
+
+// fill adds the time, stage and run totals to e
+func (t *Tracker) fill(e Event) Event {
+	e.Time = t.now()
+	e.Stage = t.stage
+	e.Queued = t.queued
+	e.Completed = t.done + t.skipped
+	e.Failed = t.failed
+	e.Tokens = t.tokens
+	e.Cost = t.cost
+
+	// Extrapolate from the files analyzed so far; skipped files take no time
+	if remaining := t.queued - e.Completed; t.done > 0 && remaining > 0 {
+		perFile := e.Time.Sub(t.started).Seconds() / float64(t.done)
+		e.ETA = perFile * float64(remaining)
+	}
+	return e
+}
@@ -0,0 +1,124 @@
+// autodoc/internal/progress/progress_test.go
+
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackerTotalsAndETA(t *testing.T) {
+	var events []Event
+	tracker := NewTracker(ReporterFunc(func(e Event) { events = append(events, e) }))
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return clock }
+
+	tracker.Stage("analyze")
+	tracker.Queue("a.go", "b.go", "c.go", "d.go")
+	tracker.Skip("a.go")
+	tracker.Start("b.go")
+	clock = clock.Add(10 * time.Second)
+	tracker.Analyzed("b.go")
+	tracker.Usage(1500, 0.25)
+	tracker.Start("c.go")
+	clock = clock.Add(10 * time.Second)
+	tracker.Fail("c.go", errors.New("model refused"))
+
+	last := events[len(events)-1]
+	if last.Type != EventFailed || last.Error != "model refused" {
+		t.Errorf("Expected a failed event with its error, got %+v", last)
+	}
+	if last.Stage != "analyze" {
+		t.Errorf("Expected stage analyze, got %q", last.Stage)
+	}
+	if last.Queued != 4 || last.Completed != 3 || last.Failed != 1 {
+		t.Errorf("Expected 3/4 completed with 1 failure, got %d/%d with %d", last.Completed, last.Queued, last.Failed)
+	}
+	if last.Tokens != 1500 || last.Cost != 0.25 {
+		t.Errorf("Expected 1500 tokens costing 0.25, got %d costing %f", last.Tokens, last.Cost)
+	}
+	// Two analyzed files took 20s, so the one remaining file takes 10s
+	if last.Remaining() != 10*time.Second {
+		t.Errorf("Expected an ETA of 10s, got %s", last.Remaining())
+	}
+
+	// Unchanged usage is not reported again
+	count := len(events)
+	tracker.Usage(1500, 0.25)
+	if len(events) != count {
+		t.Error("Expected no event for unchanged usage")
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.Stage("collect")
+	tracker.Queue("a.go")
+	tracker.Start("a.go")
+	tracker.Fail("a.go", errors.New("boom"))
+	tracker.Finish("failed")
+	if snapshot := tracker.Snapshot(); snapshot.Queued != 0 {
+		t.Errorf("Expected an empty snapshot, got %+v", snapshot)
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracker := NewTracker(NewJSONReporter(&buf))
+	tracker.Queue("a.go")
+	tracker.Start("a.go")
+	tracker.Analyzed("a.go")
+	tracker.Finish("completed")
+
+	var types []EventType
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Failed to decode event line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, e.Type)
+		if e.Type == EventFinished && (e.Status != "completed" || e.Completed != 1) {
+			t.Errorf("Expected a completed run with 1 file, got %+v", e)
+		}
+	}
+	expected := []EventType{EventQueued, EventStarted, EventAnalyzed, EventFinished}
+	if len(types) != len(expected) {
+		t.Fatalf("Expected %d events, got %v", len(expected), types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("Expected event %d to be %s, got %s", i, expected[i], types[i])
+		}
+	}
+}
+
+func TestBarReporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracker := NewTracker(NewBarReporter(&buf))
+	tracker.Queue("a.go", "b.go")
+	tracker.Start("a.go")
+	tracker.Analyzed("a.go")
+	tracker.Fail("b.go", errors.New("boom"))
+	tracker.Finish("completed")
+
+	out := buf.String()
+	if !strings.Contains(out, "1/2 files") {
+		t.Errorf("Expected the bar to show 1/2 files, got %q", out)
+	}
+	if !strings.Contains(out, "Failed to document b.go: boom\n") {
+		t.Errorf("Expected the failure on its own line, got %q", out)
+	}
+	if !strings.HasSuffix(out, "Run completed: 2/2 files, 1 failed\n") {
+		t.Errorf("Expected the run summary last, got %q", out)
+	}
+
+	if _, err := NewReporter("xml", nil); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,160 @@
+// autodoc/internal/progress/report.go
+
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Output formats accepted by NewReporter
+const (
+	FormatText = "text" // Progress bar on a terminal, one line per file otherwise
+	FormatJSON = "json" // One JSON object per event
+)
+
+// NewReporter returns the reporter for format writing to f. Text output is
+// drawn as a progress bar when f is a terminal.
+func NewReporter(format string, f *os.File) (Reporter, error) {
+	switch format {
+	case FormatJSON:
+		return NewJSONReporter(f), nil
+	case FormatText, "":
+		if IsTerminal(f) {
+			return NewBarReporter(f), nil
+		}
+		return NewTextReporter(f), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// IsTerminal reports whether f is a character device such as a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// JSONReporter writes each event as a line of JSON
+type JSONReporter struct {
+	enc *json.Encoder
+}
+
+// NewJSONReporter creates a reporter writing JSON lines to w
+func NewJSONReporter(w io.Writer) *JSONReporter {
+	return &JSONReporter{enc: json.NewEncoder(w)}
+}
+
+// Report writes e as a line of JSON
+func (r *JSONReporter) Report(e Event) {
+	r.enc.Encode(e)
+}
+
+// TextReporter writes one human-readable line per file event
+type TextReporter struct {
+	w io.Writer
+}
+
+// NewTextReporter creates a reporter writing plain lines to w
+func NewTextReporter(w io.Writer) *TextReporter {
+	return &TextReporter{w: w}
+}
+
+// Report writes a line describing e; queued and token events are not shown
+func (r *TextReporter) Report(e Event) {
+	if line := describe(e); line != "" {
+		fmt.Fprintln(r.w, line)
+	}
+}
+
+// BarReporter redraws a single progress line on a terminal, printing stage
+// changes and failures above it
+type BarReporter struct {
+	w     io.Writer
+	width int
+	drawn bool
+}
+
+// barWidth is the number of cells in the progress bar
+const barWidth = 30
+
+// NewBarReporter creates a reporter drawing a progress bar on w
+func NewBarReporter(w io.Writer) *BarReporter {
+	return &BarReporter{w: w, width: barWidth}
+}
+
+// Report redraws the progress bar for e
+func (r *BarReporter) Report(e Event) {
+	switch e.Type {
+	case EventStage, EventFailed, EventFinished:
+		r.clear()
+		fmt.Fprintln(r.w, describe(e))
+	}
+	if e.Type == EventFinished {
+		return
+	}
+
+	line := r.bar(e)
+	if e.Type == EventStarted || e.Type == EventWrote {
+		line += "  " + e.File
+	}
+	fmt.Fprint(r.w, "\r\x1b[K"+line)
+	r.drawn = true
+}
+
+// clear erases the progress line
+func (r *BarReporter) clear() {
+	if r.drawn {
+		fmt.Fprint(r.w, "\r\x1b[K")
+		r.drawn = false
+	}
+}
+
+// bar formats the progress line for e
+func (r *BarReporter) bar(e Event) string {
+	filled := 0
+	if e.Queued > 0 {
+		filled = r.width * e.Completed / e.Queued
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s%s] %d/%d files", strings.Repeat("=", filled), strings.Repeat(" ", r.width-filled), e.Completed, e.Queued)
+	if e.Failed > 0 {
+		fmt.Fprintf(&b, ", %d failed", e.Failed)
+	}
+	if e.Tokens > 0 {
+		fmt.Fprintf(&b, ", %d tokens ($%.4f)", e.Tokens, e.Cost)
+	}
+	if e.ETA > 0 {
+		fmt.Fprintf(&b, ", ETA %s", e.Remaining().Round(time.Second))
+	}
+	return b.String()
+}
+
+// describe returns a human-readable line for e, or "" for events that are
+// only shown as totals
+func describe(e Event) string {
+	switch e.Type {
+	case EventStage:
+		return "Stage: " + e.Stage
+	case EventStarted:
+		return "Analyzing file: " + e.File
+	case EventAnalyzed:
+		return "Documentation generated for: " + e.File
+	case EventSkipped:
+		return "Up to date: " + e.File
+	case EventFailed:
+		return fmt.Sprintf("Failed to document %s: %s", e.File, e.Error)
+	case EventWrote:
+		return "Wrote: " + e.File
+	case EventFinished:
+		return fmt.Sprintf("Run %s: %d/%d files, %d failed", e.Status, e.Completed, e.Queued, e.Failed)
+	}
+	return ""
+}
@@ -0,0 +1,196 @@
+// autodoc/internal/prompts/prompts.go
+
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Template names used by the analyzers
+const (
+	System           = "system"            // System prompt for structured file analysis
+	Analysis         = "analysis"          // User prompt for structured file analysis
+	ChunkHeader      = "chunk_header"      // Header describing one chunk of a large file
+	MergePurposes    = "merge_purposes"    // Combines the purposes of all chunks of a file
+	Document         = "document"          // Free-form documentation prompt
+	EnhancedSystem   = "enhanced_system"   // System prompt for architectural analysis
+	EnhancedAnalysis = "enhanced_analysis" // User prompt for architectural analysis
+)
+
+// versionFile holds the version identifier of a prompt directory
+const versionFile = "VERSION"
+
+//go:embed templates
+var defaultFS embed.FS
+
+// Data holds the values available to prompt templates
+type Data struct {
+	Language  string // Language of the file, e.g. "go"
+	FileType  string // Type of the file, e.g. "source" or "project"
+	Path      string // Path of the file
+	Content   string // Code to analyze
+	Part      int    // 1-based chunk number, for chunk headers
+	Parts     int    // Total number of chunks, for chunk headers
+	StartLine int    // First line of the chunk
+	EndLine   int    // Last line of the chunk
+	Summaries string // Per-chunk purposes, for merging
+	Context   string // Purposes of the documented files this file imports
+}
+
+// Library is a versioned set of prompt templates. A template named "analysis"
+// can be overridden for a language and file type by "go_source/analysis",
+// or for a whole language by "go/analysis".
+type Library struct {
+	version   string
+	templates map[string]*template.Template
+}
+
+// Default returns the built-in prompt templates
+func Default() *Library {
+	lib, err := load(defaultFS, "templates", nil)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in prompt templates: %v", err))
+	}
+	return lib
+}
+
+// Load returns the built-in templates overlaid with the templates found in
+// dir. Each "<name>.tmpl" file replaces the built-in template of the same
+// name, and subdirectories named "<language>_<type>" or "<language>" hold
+// overrides. The version is read from dir/VERSION; without one it is derived
+// from the contents of the overrides so that edits always change it. An
+// empty dir returns the built-in templates.
+func Load(dir string) (*Library, error) {
+	base := Default()
+	if dir == "" {
+		return base, nil
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to open prompt directory: %w", err)
+	}
+	return load(os.DirFS(dir), ".", base)
+}
+
+// load parses every template under root in fsys, on top of base if given
+func load(fsys fs.FS, root string, base *Library) (*Library, error) {
+	lib := &Library{templates: make(map[string]*template.Template)}
+	if base != nil {
+		for name, tmpl := range base.templates {
+			lib.templates[name] = tmpl
+		}
+	}
+
+	// Hash the overrides in a stable order for directories without a VERSION file
+	var names []string
+	sources := make(map[string][]byte)
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".tmpl") {
+			return nil
+		}
+		src, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt template %s: %w", p, err)
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		name := strings.TrimSuffix(rel, ".tmpl")
+
+		// Editors add a final newline; it is not part of the prompt
+		tmpl, err := template.New(name).Parse(strings.TrimRight(string(src), "\r\n"))
+		if err != nil {
+			return fmt.Errorf("failed to parse prompt template %s: %w", p, err)
+		}
+		lib.templates[name] = tmpl
+		names = append(names, name)
+		sources[name] = src
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := fs.ReadFile(fsys, path.Join(root, versionFile))
+	switch {
+	case err == nil && strings.TrimSpace(string(version)) != "":
+		lib.version = strings.TrimSpace(string(version))
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("failed to read prompt version: %w", err)
+	case base == nil:
+		return nil, fmt.Errorf("prompt templates have no %s file", versionFile)
+	default:
+		sort.Strings(names)
+		hash := sha256.New()
+		for _, name := range names {
+			fmt.Fprintf(hash, "%s\x00%s\x00", name, sources[name])
+		}
+		lib.version = base.version + "+" + hex.EncodeToString(hash.Sum(nil))[:8]
+	}
+
+	return lib, nil
+}
+
+// Version returns the version identifier of the library
+func (l *Library) Version() string {
+	return l.version
+}
+
+// Resolve returns the name of the template used for name with the given
+// language and file type: "<language>_<type>/<name>", then
+// "<language>/<name>", then "<name>"
+func (l *Library) Resolve(name, language, fileType string) string {
+	var candidates []string
+	if language != "" && fileType != "" {
+		candidates = append(candidates, language+"_"+fileType+"/"+name)
+	}
+	if language != "" {
+		candidates = append(candidates, language+"/"+name)
+	}
+	candidates = append(candidates, name)
+	for _, candidate := range candidates {
+		if _, ok := l.templates[candidate]; ok {
+			return candidate
+		}
+	}
+	return name
+}
+
+// TemplateVersion identifies the exact template used for name, language and
+// file type, e.g. "v1:go_source/analysis". It is stamped on documents so
+// pages built with older prompts can be found.
+func (l *Library) TemplateVersion(name, language, fileType string) string {
+	return l.version + ":" + l.Resolve(name, language, fileType)
+}
+
+// Render executes the template for name, language and file type with data
+func (l *Library) Render(name, language, fileType string, data Data) (string, error) {
+	resolved := l.Resolve(name, language, fileType)
+	tmpl, ok := l.templates[resolved]
+	if !ok {
+		return "", fmt.Errorf("prompt template %q not found", name)
+	}
+	if data.Language == "" {
+		data.Language = language
+	}
+	if data.FileType == "" {
+		data.FileType = fileType
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", resolved, err)
+	}
+	return buf.String(), nil
+}
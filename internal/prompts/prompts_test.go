@@ -0,0 +1,85 @@
+// autodoc/internal/prompts/prompts_test.go
+
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultLibrary(t *testing.T) {
+	lib := Default()
+	if lib.Version() == "" {
+		t.Fatal("Expected built-in prompts to have a version")
+	}
+
+	if got := lib.Resolve(Analysis, "go", "source"); got != "go_source/analysis" {
+		t.Errorf("Expected go_source/analysis, got %s", got)
+	}
+	if got := lib.Resolve(Analysis, "python", "source"); got != "analysis" {
+		t.Errorf("Expected fallback to analysis, got %s", got)
+	}
+
+	prompt, err := lib.Render(Analysis, "go", "source", Data{Content: "package main"})
+	if err != nil {
+		t.Fatalf("Failed to render prompt: %v", err)
+	}
+	if !strings.HasPrefix(prompt, "Analyze this Go code") || !strings.HasSuffix(prompt, "\n\npackage main") {
+		t.Errorf("Unexpected rendered prompt: %q", prompt)
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "go"), 0755); err != nil {
+		t.Fatalf("Failed to create override directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go", "analysis.tmpl"), []byte("Go file {{.Path}}:\n{{.Content}}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	lib, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Failed to load prompts: %v", err)
+	}
+
+	// A language-and-type override still wins over a language override
+	if got := lib.Resolve(Analysis, "go", "source"); got != "go_source/analysis" {
+		t.Errorf("Expected go_source/analysis, got %s", got)
+	}
+	prompt, err := lib.Render(Analysis, "go", "test", Data{Path: "a_test.go", Content: "code"})
+	if err != nil {
+		t.Fatalf("Failed to render prompt: %v", err)
+	}
+	if prompt != "Go file a_test.go:\ncode" {
+		t.Errorf("Unexpected rendered prompt: %q", prompt)
+	}
+
+	// Without a VERSION file the version is derived from the overrides
+	if !strings.HasPrefix(lib.Version(), Default().Version()+"+") {
+		t.Errorf("Expected derived version, got %s", lib.Version())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte("team-3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write version: %v", err)
+	}
+	lib, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Failed to load prompts: %v", err)
+	}
+	if got := lib.TemplateVersion(Analysis, "go", "test"); got != "team-3:go/analysis" {
+		t.Errorf("Expected team-3:go/analysis, got %s", got)
+	}
+}
+
+func TestLoadInvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "system.tmpl"), []byte("{{.Missing"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
@@ -224,6 +224,175 @@ func (s *BadgerStorage) BatchSaveReferences(refs []*Reference) error {
 	return wb.Flush()
 }
 
+// GetCachedResponse retrieves a cached LLM response, returning nil if none exists
+func (s *BadgerStorage) GetCachedResponse(key string) (*CachedResponse, error) {
+	var entry *CachedResponse
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("cache:" + key))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return nil
+			}
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			entry = &CachedResponse{}
+			return json.Unmarshal(val, entry)
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached response: %w", err)
+	}
+
+	return entry, nil
+}
+
+// SaveCachedResponse saves an LLM response to the cache
+func (s *BadgerStorage) SaveCachedResponse(entry *CachedResponse) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached response: %w", err)
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("cache:"+entry.Key), data)
+	})
+}
+
+// ClearResponseCache removes all cached LLM responses
+func (s *BadgerStorage) ClearResponseCache() error {
+	if err := s.db.DropPrefix([]byte("cache:")); err != nil {
+		return fmt.Errorf("failed to clear response cache: %w", err)
+	}
+	return nil
+}
+
+// SaveRun saves a documentation run
+func (s *BadgerStorage) SaveRun(run *Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("run:"+run.ID), data)
+	})
+}
+
+// GetRun retrieves a documentation run, returning nil if none exists
+func (s *BadgerStorage) GetRun(id string) (*Run, error) {
+	var run *Run
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("run:" + id))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return nil
+			}
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			run = &Run{}
+			return json.Unmarshal(val, run)
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run: %w", err)
+	}
+
+	return run, nil
+}
+
+// ListRuns lists every documentation run, oldest first
+func (s *BadgerStorage) ListRuns() ([]*Run, error) {
+	var runs []*Run
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("run:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var run Run
+				if err := json.Unmarshal(val, &run); err != nil {
+					return err
+				}
+				runs = append(runs, &run)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
+// SaveFileStates saves the state of files within a run in a batch
+func (s *BadgerStorage) SaveFileStates(states []*FileState) error {
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, state := range states {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return fmt.Errorf("failed to marshal file state: %w", err)
+		}
+
+		key := fmt.Sprintf("runfile:%s:%s", state.RunID, state.Path)
+		if err := wb.Set([]byte(key), data); err != nil {
+			return fmt.Errorf("failed to batch set file state: %w", err)
+		}
+	}
+
+	return wb.Flush()
+}
+
+// ListFileStates lists the state of every file recorded for a run
+func (s *BadgerStorage) ListFileStates(runID string) ([]*FileState, error) {
+	var states []*FileState
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("runfile:" + runID + ":")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			err := it.Item().Value(func(val []byte) error {
+				var state FileState
+				if err := json.Unmarshal(val, &state); err != nil {
+					return err
+				}
+				states = append(states, &state)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list file states: %w", err)
+	}
+
+	return states, nil
+}
+
 // SearchSimilar finds documents with similar embeddings using cosine similarity
 func (s *BadgerStorage) SearchSimilar(embedding []float64, limit int) ([]*Document, error) {
 	var docs []*Document
@@ -102,3 +102,113 @@ func TestBadgerStorage(t *testing.T) {
 		t.Errorf("Expected 1 similar document, got %d", len(similar))
 	}
 }
+
+func TestResponseCache(t *testing.T) {
+	storage, err := NewBadgerStorage(filepath.Join(t.TempDir(), "db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	// Test cache miss
+	entry, err := storage.GetCachedResponse("missing")
+	if err != nil {
+		t.Fatalf("Failed to get cached response: %v", err)
+	}
+	if entry != nil {
+		t.Errorf("Expected no cached response, got %+v", entry)
+	}
+
+	// Test SaveCachedResponse
+	if err := storage.SaveCachedResponse(&CachedResponse{
+		Key:           "key1",
+		Model:         "model",
+		PromptVersion: "v1",
+		Response:      `{"purpose":"test"}`,
+		CreatedAt:     time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to save cached response: %v", err)
+	}
+
+	entry, err = storage.GetCachedResponse("key1")
+	if err != nil {
+		t.Fatalf("Failed to get cached response: %v", err)
+	}
+	if entry == nil || entry.Response != `{"purpose":"test"}` {
+		t.Errorf("Expected cached response, got %+v", entry)
+	}
+
+	// Test ClearResponseCache
+	if err := storage.ClearResponseCache(); err != nil {
+		t.Fatalf("Failed to clear response cache: %v", err)
+	}
+	entry, err = storage.GetCachedResponse("key1")
+	if err != nil {
+		t.Fatalf("Failed to get cached response: %v", err)
+	}
+	if entry != nil {
+		t.Errorf("Expected cache to be empty, got %+v", entry)
+	}
+}
+
+func TestRunStore(t *testing.T) {
+	storage, err := NewBadgerStorage(filepath.Join(t.TempDir(), "db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	// Test run miss
+	run, err := storage.GetRun("missing")
+	if err != nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	if run != nil {
+		t.Errorf("Expected no run, got %+v", run)
+	}
+
+	// Test SaveRun
+	if err := storage.SaveRun(&Run{ID: "run1", Source: "/src", Status: RunRunning, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+	run, err = storage.GetRun("run1")
+	if err != nil {
+		t.Fatalf("Failed to get run: %v", err)
+	}
+	if run == nil || run.Status != RunRunning {
+		t.Errorf("Expected running run, got %+v", run)
+	}
+
+	// Test file states are kept per run
+	if err := storage.SaveFileStates([]*FileState{
+		{RunID: "run1", Path: "a.go", Status: FileDone},
+		{RunID: "run1", Path: "b.go", Status: FileFailed, Error: "boom"},
+		{RunID: "run10", Path: "c.go", Status: FilePending},
+	}); err != nil {
+		t.Fatalf("Failed to save file states: %v", err)
+	}
+	states, err := storage.ListFileStates("run1")
+	if err != nil {
+		t.Fatalf("Failed to list file states: %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("Expected 2 file states, got %d", len(states))
+	}
+	for _, state := range states {
+		if state.Path == "b.go" && state.Error != "boom" {
+			t.Errorf("Expected error to be kept, got %q", state.Error)
+		}
+	}
+
+	// Test ListRuns returns runs oldest first
+	if err := storage.SaveRun(&Run{ID: "run0", Status: RunFailed, CreatedAt: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+	runs, err := storage.ListRuns()
+	if err != nil {
+		t.Fatalf("Failed to list runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "run0" || runs[1].ID != "run1" {
+		t.Errorf("Expected runs run0 and run1, got %+v", runs)
+	}
+}
@@ -25,6 +25,8 @@ type ComponentInfo struct {
 	Visibility      string   `json:"visibility"`
 	Dependencies    []string `json:"dependencies"`
 	NotableFeatures []string `json:"notable_features"`
+	StartLine       int      `json:"start_line,omitempty"` // First source line, when known
+	EndLine         int      `json:"end_line,omitempty"`   // Last source line, when known
 }
 
 // RelationInfo represents a relationship between components
@@ -36,18 +38,20 @@ type RelationInfo struct {
 
 // Document represents a piece of documentation
 type Document struct {
-	ID         string          `json:"id"`         // Unique identifier
-	Path       string          `json:"path"`       // File path this document relates to
-	Type       DocumentType    `json:"type"`       // Type of documentation
-	Content    string          `json:"content"`    // The actual documentation content
-	Purpose    string          `json:"purpose"`    // Brief description of the code's purpose
-	Components []ComponentInfo `json:"components"` // List of components in this document
-	Relations  []RelationInfo  `json:"relations"`  // List of relationships
-	Insights   []string        `json:"insights"`   // Important observations
-	Embedding  []float64       `json:"embedding"`  // Vector embedding for semantic search
-	References []string        `json:"references"` // List of other document IDs this references
-	CreatedAt  time.Time       `json:"created_at"`
-	UpdatedAt  time.Time       `json:"updated_at"`
+	ID            string          `json:"id"`                       // Unique identifier
+	Path          string          `json:"path"`                     // File path this document relates to
+	Type          DocumentType    `json:"type"`                     // Type of documentation
+	Content       string          `json:"content"`                  // The actual documentation content
+	Purpose       string          `json:"purpose"`                  // Brief description of the code's purpose
+	Components    []ComponentInfo `json:"components"`               // List of components in this document
+	Relations     []RelationInfo  `json:"relations"`                // List of relationships
+	Insights      []string        `json:"insights"`                 // Important observations
+	Embedding     []float64       `json:"embedding"`                // Vector embedding for semantic search
+	References    []string        `json:"references"`               // List of other document IDs this references
+	PromptVersion string          `json:"prompt_version,omitempty"` // Prompt template that produced this document
+	Commit        string          `json:"commit,omitempty"`         // SHA of the commit the document was built from
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
 }
 
 // Reference represents a relationship between two pieces of code/documentation
@@ -75,3 +79,74 @@ type Storage interface {
 	BatchSaveDocuments(docs []*Document) error
 	BatchSaveReferences(refs []*Reference) error
 }
+
+// CachedResponse is a raw LLM response stored for reuse across runs
+type CachedResponse struct {
+	Key           string    `json:"key"`            // Hash of content, prompt version and model
+	Model         string    `json:"model"`          // Model that produced the response
+	PromptVersion string    `json:"prompt_version"` // Version of the prompt template used
+	Response      string    `json:"response"`       // Raw response content
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ResponseCache defines the methods required for caching LLM responses
+type ResponseCache interface {
+	// GetCachedResponse returns the cached response for key, or nil if there is none
+	GetCachedResponse(key string) (*CachedResponse, error)
+	SaveCachedResponse(entry *CachedResponse) error
+	// ClearResponseCache removes every cached response
+	ClearResponseCache() error
+}
+
+// RunStatus is the state of a documentation run
+type RunStatus string
+
+const (
+	RunRunning     RunStatus = "running"
+	RunCompleted   RunStatus = "completed"
+	RunFailed      RunStatus = "failed"
+	RunIncomplete  RunStatus = "incomplete"  // Stopped early, e.g. by a budget
+	RunInterrupted RunStatus = "interrupted" // Cancelled by a signal
+)
+
+// FileStatus is the state of one file within a run
+type FileStatus string
+
+const (
+	FilePending FileStatus = "pending"
+	FileDone    FileStatus = "done"
+	FileFailed  FileStatus = "failed"
+)
+
+// Run records a documentation run so it can be resumed
+type Run struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"` // Repository path or URL being documented
+	Status    RunStatus `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FileState records the progress of one file within a run
+type FileState struct {
+	RunID       string     `json:"run_id"`
+	Path        string     `json:"path"`
+	Status      FileStatus `json:"status"`
+	Error       string     `json:"error,omitempty"`        // Last failure, if any
+	Stage       string     `json:"stage,omitempty"`        // Pipeline stage of the last failure
+	RawResponse string     `json:"raw_response,omitempty"` // Analyzer response of the last failure
+	Attempts    int        `json:"attempts"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// RunStore defines the methods required for checkpointing runs
+type RunStore interface {
+	SaveRun(run *Run) error
+	// GetRun returns the run with the given ID, or nil if there is none
+	GetRun(id string) (*Run, error)
+	// ListRuns returns every recorded run, oldest first
+	ListRuns() ([]*Run, error)
+	SaveFileStates(states []*FileState) error
+	// ListFileStates returns the state of every file recorded for a run
+	ListFileStates(runID string) ([]*FileState, error)
+}
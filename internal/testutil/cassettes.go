@@ -0,0 +1,58 @@
+// internal/testutil/cassettes.go
+
+package testutil
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	analyzer "github.com/rgehrsitz/AutoDoc/internal/analysis"
+	"github.com/rgehrsitz/AutoDoc/internal/collector"
+	"github.com/rgehrsitz/AutoDoc/internal/llm"
+	"github.com/rgehrsitz/AutoDoc/pkg/config"
+)
+
+// CalculatorPath and CalculatorSource are the file recorded in the
+// analysis cassettes
+const (
+	CalculatorPath   = "math/calculator.go"
+	CalculatorSource = `package math
+
+// Calculator performs basic arithmetic.
+type Calculator struct{}
+
+// Add returns the sum of a and b.
+func (c *Calculator) Add(a, b int) int {
+	return a + b
+}
+`
+)
+
+// ReplayCalculator analyzes CalculatorSource with a provider replaying the
+// cassettes of internal/analysis
+func ReplayCalculator(t *testing.T) *analyzer.Analysis {
+	t.Helper()
+
+	_, file, _, _ := runtime.Caller(0)
+	provider, err := llm.NewProvider(config.LLMConfig{
+		Model:        config.DefaultLLMModel,
+		CassetteMode: config.CassetteReplay,
+		CassetteDir:  filepath.Join(filepath.Dir(file), "..", "analysis", "testdata", "cassettes"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create replay provider: %v", err)
+	}
+
+	analysis, raw, err := analyzer.NewAnalyzer(provider).AnalyzeFile(context.Background(), collector.FileInfo{
+		Path:     CalculatorPath,
+		Language: "go",
+		Type:     "source",
+		Content:  CalculatorSource,
+	})
+	if err != nil {
+		t.Fatalf("Failed to analyze file: %v (raw response: %s)", err, raw)
+	}
+	return analysis
+}
@@ -4,8 +4,11 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the configuration settings for AutoDoc.
@@ -15,13 +18,87 @@ type Config struct {
 	ProjectURL   string
 	Theme        string
 	CustomStyles map[string]string
+	LLM          LLMConfig
+	Files        FilesConfig
+}
+
+// FilesConfig selects the files of a repository that are documented
+type FilesConfig struct {
+	Include          []string // Globs a file must match to be documented (empty for all files)
+	Exclude          []string // Globs of files never documented
+	MaxFileSize      int64    // Largest file documented, in bytes (0 for no limit)
+	IncludeGenerated bool     // Document files marked as generated code
+}
+
+// DefaultMaxFileSize is the largest file documented unless configured otherwise
+const DefaultMaxFileSize = 1 << 20
+
+// LLMConfig holds the settings used to build an LLM provider.
+type LLMConfig struct {
+	Provider          string                // Provider name (e.g., "openai")
+	APIKey            string                // API key for the provider (optional for self-hosted endpoints)
+	BaseURL           string                // OpenAI-compatible endpoint (empty for the provider default)
+	Headers           map[string]string     // Extra HTTP headers sent with every request
+	Model             string                // Chat completion model
+	EmbeddingModel    string                // Embedding model
+	Temperature       float64               // Sampling temperature
+	MaxTokens         int64                 // Maximum completion tokens (0 for provider default)
+	ContextTokens     int                   // Model context window in tokens (0 to look it up by model name)
+	Timeout           time.Duration         // Per-request timeout (0 for no timeout)
+	StructuredOutput  bool                  // Send JSON-schema response formats to the provider
+	RepairAttempts    int                   // Extra attempts when a structured response fails validation
+	CassetteMode      string                // "record", "replay" or empty to disable cassettes
+	CassetteDir       string                // Directory holding recorded LLM interactions
+	Prices            map[string]ModelPrice // Price overrides keyed by model name prefix
+	PromptsDir        string                // Directory of prompt templates overriding the built-in ones
+	RequestsPerMinute int                   // Request rate limit (0 for no limit)
+	TokensPerMinute   int                   // Token rate limit (0 for no limit)
+	RateLimitFile     string                // State file shared by processes drawing on one quota
+	MinConcurrency    int                   // Fewest LLM calls kept in flight when throttled
+	MaxConcurrency    int                   // Most LLM calls in flight while calls succeed
+}
+
+// ModelPrice is the cost of a model in US dollars per million tokens
+type ModelPrice struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// Cassette modes for recording and replaying LLM traffic
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// Default LLM settings used when the corresponding environment variable is unset.
+const (
+	ProviderOffline       = "offline" // Static analysis only, no LLM calls
+	DefaultLLMProvider    = "openai"
+	DefaultLLMModel       = "chatgpt-4o-latest"
+	DefaultEmbeddingModel = "text-embedding-3-small"
+	DefaultLLMTemperature = 0.3
+	DefaultLLMTimeout     = 2 * time.Minute
+	DefaultRepairAttempts = 2
+	DefaultCassetteDir    = "testdata/cassettes"
+	DefaultRequestsPerMin = 60
+	DefaultMinConcurrency = 1
+	DefaultMaxConcurrency = 8
+)
+
+// Offline reports whether analysis should run without any LLM calls
+func (c LLMConfig) Offline() bool {
+	return c.Provider == ProviderOffline
 }
 
 // LoadConfig loads configuration from environment variables or a config file.
-// For simplicity, this example uses environment variables.
+// For simplicity, this example uses environment variables. The API key is only
+// required when no custom base URL is configured, since self-hosted endpoints
+// usually need no key, and never for the offline provider or cassette replay.
 func LoadConfig() (*Config, error) {
 	openAIKey := os.Getenv("OPENAI_API_KEY")
-	if openAIKey == "" {
+	offline := strings.EqualFold(os.Getenv("AUTODOC_LLM_PROVIDER"), ProviderOffline) ||
+		strings.EqualFold(os.Getenv("AUTODOC_LLM_CASSETTE"), CassetteReplay)
+	if openAIKey == "" && baseURLFromEnv() == "" && !offline {
 		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is not set")
 	}
 
@@ -53,15 +130,244 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	llmConfig, err := loadLLMConfig(openAIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	filesConfig, err := loadFilesConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		OpenAIKey:    openAIKey,
 		ProjectName:  projectName,
 		ProjectURL:   projectURL,
 		Theme:        theme,
 		CustomStyles: customStyles,
+		LLM:          llmConfig,
+		Files:        filesConfig,
 	}, nil
 }
 
+// loadFilesConfig reads the file selection settings from environment variables.
+func loadFilesConfig() (FilesConfig, error) {
+	cfg := FilesConfig{
+		Include:     splitAndTrim(os.Getenv("AUTODOC_INCLUDE"), ","),
+		Exclude:     splitAndTrim(os.Getenv("AUTODOC_EXCLUDE"), ","),
+		MaxFileSize: DefaultMaxFileSize,
+	}
+
+	if v := os.Getenv("AUTODOC_MAX_FILE_SIZE"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size < 0 {
+			return cfg, fmt.Errorf("invalid AUTODOC_MAX_FILE_SIZE %q", v)
+		}
+		cfg.MaxFileSize = size
+	}
+
+	if v := os.Getenv("AUTODOC_INCLUDE_GENERATED"); v != "" {
+		include, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid AUTODOC_INCLUDE_GENERATED %q: %w", v, err)
+		}
+		cfg.IncludeGenerated = include
+	}
+
+	return cfg, nil
+}
+
+// loadLLMConfig reads the LLM provider settings from environment variables.
+func loadLLMConfig(apiKey string) (LLMConfig, error) {
+	cfg := LLMConfig{
+		Provider:          strings.ToLower(envOrDefault("AUTODOC_LLM_PROVIDER", DefaultLLMProvider)),
+		APIKey:            apiKey,
+		BaseURL:           baseURLFromEnv(),
+		Headers:           make(map[string]string),
+		Model:             envOrDefault("AUTODOC_LLM_MODEL", DefaultLLMModel),
+		EmbeddingModel:    envOrDefault("AUTODOC_EMBEDDING_MODEL", DefaultEmbeddingModel),
+		Temperature:       DefaultLLMTemperature,
+		Timeout:           DefaultLLMTimeout,
+		StructuredOutput:  true,
+		RepairAttempts:    DefaultRepairAttempts,
+		CassetteMode:      strings.ToLower(os.Getenv("AUTODOC_LLM_CASSETTE")),
+		CassetteDir:       envOrDefault("AUTODOC_LLM_CASSETTE_DIR", DefaultCassetteDir),
+		Prices:            make(map[string]ModelPrice),
+		PromptsDir:        os.Getenv("AUTODOC_PROMPTS_DIR"),
+		RequestsPerMinute: DefaultRequestsPerMin,
+		RateLimitFile:     os.Getenv("AUTODOC_LLM_RATE_LIMIT_FILE"),
+		MinConcurrency:    DefaultMinConcurrency,
+		MaxConcurrency:    DefaultMaxConcurrency,
+	}
+
+	switch cfg.CassetteMode {
+	case "", CassetteRecord, CassetteReplay:
+	default:
+		return cfg, fmt.Errorf("invalid AUTODOC_LLM_CASSETTE %q (expected %s or %s)", cfg.CassetteMode, CassetteRecord, CassetteReplay)
+	}
+
+	if cfg.BaseURL != "" {
+		u, err := url.Parse(cfg.BaseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return cfg, fmt.Errorf("invalid LLM base URL %q", cfg.BaseURL)
+		}
+		// The SDK resolves endpoint paths relative to the base URL
+		if !strings.HasSuffix(cfg.BaseURL, "/") {
+			cfg.BaseURL += "/"
+		}
+	}
+
+	// Expecting headers in Key1=Value1;Key2=Value2 format
+	if headers := os.Getenv("AUTODOC_LLM_HEADERS"); headers != "" {
+		for _, pair := range splitAndTrim(headers, ";") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+				return cfg, fmt.Errorf("invalid AUTODOC_LLM_HEADERS entry %q", pair)
+			}
+			cfg.Headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_TEMPERATURE"); v != "" {
+		temperature, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_TEMPERATURE %q: %w", v, err)
+		}
+		cfg.Temperature = temperature
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_MAX_TOKENS"); v != "" {
+		maxTokens, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_MAX_TOKENS %q: %w", v, err)
+		}
+		cfg.MaxTokens = maxTokens
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_CONTEXT_TOKENS"); v != "" {
+		contextTokens, err := strconv.Atoi(v)
+		if err != nil || contextTokens < 0 {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_CONTEXT_TOKENS %q", v)
+		}
+		cfg.ContextTokens = contextTokens
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_STRUCTURED_OUTPUT"); v != "" {
+		structured, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_STRUCTURED_OUTPUT %q: %w", v, err)
+		}
+		cfg.StructuredOutput = structured
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_REPAIR_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil || attempts < 0 {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_REPAIR_ATTEMPTS %q", v)
+		}
+		cfg.RepairAttempts = attempts
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_RPM"); v != "" {
+		rpm, err := strconv.Atoi(v)
+		if err != nil || rpm < 0 {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_RPM %q", v)
+		}
+		cfg.RequestsPerMinute = rpm
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_TPM"); v != "" {
+		tpm, err := strconv.Atoi(v)
+		if err != nil || tpm < 0 {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_TPM %q", v)
+		}
+		cfg.TokensPerMinute = tpm
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_MIN_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_MIN_CONCURRENCY %q", v)
+		}
+		cfg.MinConcurrency = n
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_MAX_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_MAX_CONCURRENCY %q", v)
+		}
+		cfg.MaxConcurrency = n
+	}
+	if cfg.MaxConcurrency < cfg.MinConcurrency {
+		return cfg, fmt.Errorf("AUTODOC_LLM_MAX_CONCURRENCY (%d) is below AUTODOC_LLM_MIN_CONCURRENCY (%d)", cfg.MaxConcurrency, cfg.MinConcurrency)
+	}
+
+	if v := os.Getenv("AUTODOC_LLM_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid AUTODOC_LLM_TIMEOUT %q: %w", v, err)
+		}
+		cfg.Timeout = timeout
+	}
+
+	// Expecting prices in model=prompt/completion;model2=prompt/completion format
+	if prices := os.Getenv("AUTODOC_LLM_PRICES"); prices != "" {
+		for _, entry := range splitAndTrim(prices, ";") {
+			model, price, err := parseModelPrice(entry)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid AUTODOC_LLM_PRICES entry %q: %w", entry, err)
+			}
+			cfg.Prices[model] = price
+		}
+	}
+
+	return cfg, nil
+}
+
+// parseModelPrice parses a "model=prompt/completion" price entry. The
+// completion price may be omitted for embedding models.
+func parseModelPrice(entry string) (string, ModelPrice, error) {
+	model, prices, ok := strings.Cut(entry, "=")
+	model = strings.TrimSpace(model)
+	if !ok || model == "" {
+		return "", ModelPrice{}, fmt.Errorf("expected model=prompt/completion")
+	}
+
+	var price ModelPrice
+	promptStr, completionStr, _ := strings.Cut(prices, "/")
+	prompt, err := strconv.ParseFloat(strings.TrimSpace(promptStr), 64)
+	if err != nil || prompt < 0 {
+		return "", ModelPrice{}, fmt.Errorf("invalid prompt price %q", promptStr)
+	}
+	price.Prompt = prompt
+	if strings.TrimSpace(completionStr) != "" {
+		completion, err := strconv.ParseFloat(strings.TrimSpace(completionStr), 64)
+		if err != nil || completion < 0 {
+			return "", ModelPrice{}, fmt.Errorf("invalid completion price %q", completionStr)
+		}
+		price.Completion = completion
+	}
+	return model, price, nil
+}
+
+// baseURLFromEnv returns the configured OpenAI-compatible base URL, if any
+func baseURLFromEnv() string {
+	if v := os.Getenv("AUTODOC_LLM_BASE_URL"); v != "" {
+		return v
+	}
+	return os.Getenv("OPENAI_BASE_URL")
+}
+
+// envOrDefault returns the value of the environment variable or the fallback if unset.
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
 // splitAndTrim splits a string by the given separator and trims each part.
 func splitAndTrim(s, sep string) []string {
 	parts := []string{}
@@ -0,0 +1,218 @@
+// autodoc/pkg/config/config_test.go
+
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// clearLLMEnv unsets the variables that would otherwise leak into a test
+// from the environment it runs in
+func clearLLMEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"AUTODOC_LLM_PROVIDER", "AUTODOC_LLM_MODEL", "AUTODOC_LLM_BASE_URL", "OPENAI_BASE_URL",
+		"AUTODOC_LLM_HEADERS", "AUTODOC_LLM_PRICES", "AUTODOC_LLM_CASSETTE", "AUTODOC_LLM_TIMEOUT",
+		"AUTODOC_LLM_TEMPERATURE", "AUTODOC_LLM_MAX_TOKENS", "AUTODOC_LLM_CONTEXT_TOKENS",
+		"AUTODOC_LLM_STRUCTURED_OUTPUT", "AUTODOC_LLM_REPAIR_ATTEMPTS", "AUTODOC_LLM_RPM",
+		"AUTODOC_LLM_TPM", "AUTODOC_LLM_MIN_CONCURRENCY", "AUTODOC_LLM_MAX_CONCURRENCY",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestLoadLLMConfigDefaults(t *testing.T) {
+	clearLLMEnv(t)
+
+	cfg, err := loadLLMConfig("key")
+	if err != nil {
+		t.Fatalf("Failed to load LLM config: %v", err)
+	}
+	if cfg.Provider != DefaultLLMProvider || cfg.Model != DefaultLLMModel {
+		t.Errorf("Expected %s/%s, got %s/%s", DefaultLLMProvider, DefaultLLMModel, cfg.Provider, cfg.Model)
+	}
+	if cfg.Timeout != DefaultLLMTimeout || cfg.RepairAttempts != DefaultRepairAttempts {
+		t.Errorf("Expected default timeout and repair attempts, got %s and %d", cfg.Timeout, cfg.RepairAttempts)
+	}
+	if len(cfg.Headers) != 0 || len(cfg.Prices) != 0 {
+		t.Errorf("Expected no headers or prices, got %v and %v", cfg.Headers, cfg.Prices)
+	}
+}
+
+func TestLoadLLMConfigHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "single", value: "X-Org=acme", want: map[string]string{"X-Org": "acme"}},
+		{name: "several with spaces", value: " X-Org = acme ; X-Team=docs ", want: map[string]string{"X-Org": "acme", "X-Team": "docs"}},
+		{name: "value containing equals", value: "Authorization=Basic dXNlcjpwYXNz==", want: map[string]string{"Authorization": "Basic dXNlcjpwYXNz=="}},
+		{name: "empty entries skipped", value: "X-Org=acme;;", want: map[string]string{"X-Org": "acme"}},
+		{name: "empty value", value: "X-Empty=", want: map[string]string{"X-Empty": ""}},
+		{name: "missing equals", value: "X-Org", wantErr: true},
+		{name: "missing name", value: "=acme", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearLLMEnv(t)
+			t.Setenv("AUTODOC_LLM_HEADERS", tt.value)
+
+			cfg, err := loadLLMConfig("key")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error for %q, got headers %v", tt.value, cfg.Headers)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load LLM config: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.Headers, tt.want) {
+				t.Errorf("Expected headers %v, got %v", tt.want, cfg.Headers)
+			}
+		})
+	}
+}
+
+func TestParseModelPrice(t *testing.T) {
+	tests := []struct {
+		entry   string
+		model   string
+		price   ModelPrice
+		wantErr bool
+	}{
+		{entry: "gpt-4o=2.5/10", model: "gpt-4o", price: ModelPrice{Prompt: 2.5, Completion: 10}},
+		{entry: " local-llama = 0 / 0 ", model: "local-llama", price: ModelPrice{}},
+		{entry: "text-embedding-3-small=0.02", model: "text-embedding-3-small", price: ModelPrice{Prompt: 0.02}},
+		{entry: "text-embedding-3-small=0.02/", model: "text-embedding-3-small", price: ModelPrice{Prompt: 0.02}},
+		{entry: "gpt-4o", wantErr: true},
+		{entry: "=2.5/10", wantErr: true},
+		{entry: "gpt-4o=/10", wantErr: true},
+		{entry: "gpt-4o=cheap/10", wantErr: true},
+		{entry: "gpt-4o=2.5/lots", wantErr: true},
+		{entry: "gpt-4o=-1/10", wantErr: true},
+		{entry: "gpt-4o=2.5/-10", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			model, price, err := parseModelPrice(tt.entry)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error for %q, got %s %+v", tt.entry, model, price)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to parse price: %v", err)
+			}
+			if model != tt.model || price != tt.price {
+				t.Errorf("Expected %s %+v, got %s %+v", tt.model, tt.price, model, price)
+			}
+		})
+	}
+}
+
+func TestLoadLLMConfigPrices(t *testing.T) {
+	clearLLMEnv(t)
+	t.Setenv("AUTODOC_LLM_PRICES", "gpt-4o=2.5/10; my-model=1/2")
+
+	cfg, err := loadLLMConfig("key")
+	if err != nil {
+		t.Fatalf("Failed to load LLM config: %v", err)
+	}
+	want := map[string]ModelPrice{"gpt-4o": {Prompt: 2.5, Completion: 10}, "my-model": {Prompt: 1, Completion: 2}}
+	if !reflect.DeepEqual(cfg.Prices, want) {
+		t.Errorf("Expected prices %v, got %v", want, cfg.Prices)
+	}
+
+	t.Setenv("AUTODOC_LLM_PRICES", "gpt-4o=2.5/10;broken")
+	if _, err := loadLLMConfig("key"); err == nil {
+		t.Error("Expected an error for an invalid price entry")
+	}
+}
+
+func TestLoadLLMConfigInvalidValues(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+	}{
+		{"AUTODOC_LLM_CASSETTE", "rewind"},
+		{"AUTODOC_LLM_BASE_URL", "localhost:8080"},
+		{"AUTODOC_LLM_TEMPERATURE", "warm"},
+		{"AUTODOC_LLM_MAX_TOKENS", "many"},
+		{"AUTODOC_LLM_CONTEXT_TOKENS", "-1"},
+		{"AUTODOC_LLM_STRUCTURED_OUTPUT", "maybe"},
+		{"AUTODOC_LLM_REPAIR_ATTEMPTS", "-2"},
+		{"AUTODOC_LLM_RPM", "-60"},
+		{"AUTODOC_LLM_TPM", "lots"},
+		{"AUTODOC_LLM_MIN_CONCURRENCY", "0"},
+		{"AUTODOC_LLM_MAX_CONCURRENCY", "none"},
+		{"AUTODOC_LLM_TIMEOUT", "90"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			clearLLMEnv(t)
+			t.Setenv(tt.key, tt.value)
+			if _, err := loadLLMConfig("key"); err == nil {
+				t.Errorf("Expected an error for %s=%q", tt.key, tt.value)
+			}
+		})
+	}
+
+	// The maximum concurrency may not be below the minimum
+	clearLLMEnv(t)
+	t.Setenv("AUTODOC_LLM_MIN_CONCURRENCY", "4")
+	t.Setenv("AUTODOC_LLM_MAX_CONCURRENCY", "2")
+	if _, err := loadLLMConfig("key"); err == nil {
+		t.Error("Expected an error for a maximum concurrency below the minimum")
+	}
+}
+
+func TestLoadLLMConfigValues(t *testing.T) {
+	clearLLMEnv(t)
+	t.Setenv("AUTODOC_LLM_BASE_URL", "http://localhost:11434/v1")
+	t.Setenv("AUTODOC_LLM_TIMEOUT", "90s")
+	t.Setenv("AUTODOC_LLM_MAX_CONCURRENCY", "3")
+
+	cfg, err := loadLLMConfig("")
+	if err != nil {
+		t.Fatalf("Failed to load LLM config: %v", err)
+	}
+	if cfg.BaseURL != "http://localhost:11434/v1/" {
+		t.Errorf("Expected the base URL with a trailing slash, got %s", cfg.BaseURL)
+	}
+	if cfg.Timeout != 90*time.Second || cfg.MaxConcurrency != 3 {
+		t.Errorf("Expected a 90s timeout and 3 workers, got %s and %d", cfg.Timeout, cfg.MaxConcurrency)
+	}
+}
+
+func TestLoadFilesConfig(t *testing.T) {
+	t.Setenv("AUTODOC_INCLUDE", "cmd/, internal/ ")
+	t.Setenv("AUTODOC_EXCLUDE", "")
+	t.Setenv("AUTODOC_MAX_FILE_SIZE", "2048")
+	t.Setenv("AUTODOC_INCLUDE_GENERATED", "true")
+
+	cfg, err := loadFilesConfig()
+	if err != nil {
+		t.Fatalf("Failed to load files config: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Include, []string{"cmd/", "internal/"}) || len(cfg.Exclude) != 0 {
+		t.Errorf("Expected include [cmd/ internal/] and no exclude, got %v and %v", cfg.Include, cfg.Exclude)
+	}
+	if cfg.MaxFileSize != 2048 || !cfg.IncludeGenerated {
+		t.Errorf("Expected 2048 bytes and generated files, got %d and %v", cfg.MaxFileSize, cfg.IncludeGenerated)
+	}
+
+	for key, value := range map[string]string{"AUTODOC_MAX_FILE_SIZE": "-1", "AUTODOC_INCLUDE_GENERATED": "sometimes"} {
+		t.Setenv("AUTODOC_MAX_FILE_SIZE", "")
+		t.Setenv("AUTODOC_INCLUDE_GENERATED", "")
+		t.Setenv(key, value)
+		if _, err := loadFilesConfig(); err == nil {
+			t.Errorf("Expected an error for %s=%q", key, value)
+		}
+	}
+}
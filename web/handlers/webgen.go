@@ -6,7 +6,6 @@ import (
 	"embed"
 	"fmt"
 	"html/template"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -16,16 +15,18 @@ import (
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/html"
 	"github.com/gomarkdown/markdown/parser"
+	"github.com/rgehrsitz/AutoDoc/internal/progress"
 	"github.com/rgehrsitz/AutoDoc/internal/storage"
 	"github.com/rgehrsitz/AutoDoc/internal/templateutil"
 )
 
-//go:embed templates/layouts/*.html templates/components/*.html templates/partials/*.html templates/assets/css/*.css templates/assets/js/*.js
+//go:embed templates/*.html templates/layouts/*.html templates/components/*.html templates/partials/*.html templates/assets/css/*.css templates/assets/js/*.js
 var embeddedTemplates embed.FS
 
 // Generator handles the wiki generation process
 type Generator struct {
-	store storage.Storage
+	store    storage.Storage
+	progress *progress.Tracker
 }
 
 // Config contains configuration for wiki generation
@@ -44,41 +45,48 @@ func NewGenerator(store storage.Storage) *Generator {
 	}
 }
 
+// SetProgress reports each generation stage to tracker
+func (g *Generator) SetProgress(tracker *progress.Tracker) {
+	g.progress = tracker
+}
+
 // Generate generates the complete wiki
 func (g *Generator) Generate(cfg Config) error {
-	log.Println("Starting wiki generation with configuration:")
-	log.Printf("OutputDir: %s", cfg.OutputDir)
-	log.Printf("ProjectName: %s", cfg.ProjectName)
-	log.Printf("ProjectURL: %s", cfg.ProjectURL)
-	log.Printf("Theme: %s", cfg.Theme)
+	if cfg.Theme == "" {
+		cfg.Theme = "light"
+	}
 
 	// Create output directory
 	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
-		log.Printf("Error creating output directory: %v", err)
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Generate index page
+	g.progress.Stage("index")
 	if err := g.generateIndex(cfg); err != nil {
 		return fmt.Errorf("failed to generate index: %w", err)
 	}
 
 	// Generate architecture documentation
+	g.progress.Stage("architecture")
 	if err := g.generateArchitecture(cfg); err != nil {
 		return fmt.Errorf("failed to generate architecture docs: %w", err)
 	}
 
 	// Generate module documentation
+	g.progress.Stage("modules")
 	if err := g.generateModules(cfg); err != nil {
 		return fmt.Errorf("failed to generate module docs: %w", err)
 	}
 
 	// Generate search page
+	g.progress.Stage("search")
 	if err := g.generateSearch(cfg); err != nil {
 		return fmt.Errorf("failed to generate search page: %w", err)
 	}
 
 	// Copy static assets from embedded files
+	g.progress.Stage("assets")
 	if err := g.copyAssets(cfg); err != nil {
 		return fmt.Errorf("failed to copy assets: %w", err)
 	}
@@ -91,12 +99,31 @@ type PageData struct {
 	Title       string
 	ProjectName string
 	ProjectURL  string
-	NavItems    []templateutil.NavItem // Use NavItem from helpers package
+	Description string
+	Navigation  []templateutil.NavItem // Use NavItem from helpers package
+	Components  []PageLink             // Pages listed for searching
+	CurrentPath string                 // Path of a module page, empty for top-level pages
 	Content     template.HTML
 	LastUpdated time.Time
 	Theme       string
 }
 
+// PageLink links to a module page from the search page
+type PageLink struct {
+	Name        string
+	URL         string
+	Description string
+}
+
+// render renders the named template to path and reports the written page
+func (g *Generator) render(path, name string, data PageData) error {
+	if err := templateutil.RenderTemplate(path, name, data, embeddedTemplates); err != nil {
+		return err
+	}
+	g.progress.Wrote(path)
+	return nil
+}
+
 func (g *Generator) generateIndex(cfg Config) error {
 	// Get architecture document for overview
 	archDocs, err := g.store.ListDocuments(storage.TypeArchitecture)
@@ -127,13 +154,13 @@ func (g *Generator) generateIndex(cfg Config) error {
 		Title:       "Home",
 		ProjectName: cfg.ProjectName,
 		ProjectURL:  cfg.ProjectURL,
-		NavItems:    nav,
+		Navigation:  nav,
 		Content:     template.HTML(renderMarkdown(overview)),
 		LastUpdated: time.Now(),
 		Theme:       cfg.Theme,
 	}
 
-	return templateutil.RenderTemplate(filepath.Join(cfg.OutputDir, "index.html"), "index", data, embeddedTemplates)
+	return g.render(filepath.Join(cfg.OutputDir, "index.html"), "index", data)
 }
 
 func (g *Generator) generateArchitecture(cfg Config) error {
@@ -157,13 +184,13 @@ func (g *Generator) generateArchitecture(cfg Config) error {
 		Title:       "Architecture",
 		ProjectName: cfg.ProjectName,
 		ProjectURL:  cfg.ProjectURL,
-		NavItems:    nav,
+		Navigation:  nav,
 		Content:     template.HTML(renderMarkdown(doc.Content)),
 		LastUpdated: doc.UpdatedAt,
 		Theme:       cfg.Theme,
 	}
 
-	return templateutil.RenderTemplate(filepath.Join(cfg.OutputDir, "architecture.html"), "page", data, embeddedTemplates)
+	return g.render(filepath.Join(cfg.OutputDir, "architecture.html"), "page", data)
 }
 
 func (g *Generator) generateModules(cfg Config) error {
@@ -225,7 +252,8 @@ func (g *Generator) generateModules(cfg Config) error {
 			Title:       cleanPath,
 			ProjectName: cfg.ProjectName,
 			ProjectURL:  cfg.ProjectURL,
-			NavItems:    nav,
+			Navigation:  nav,
+			CurrentPath: cleanPath,
 			Content:     template.HTML(renderMarkdown(content.String())),
 			LastUpdated: doc.UpdatedAt,
 			Theme:       cfg.Theme,
@@ -237,7 +265,7 @@ func (g *Generator) generateModules(cfg Config) error {
 			return fmt.Errorf("failed to create directory: %w", err)
 		}
 
-		if err := templateutil.RenderTemplate(outPath, "page", data, embeddedTemplates); err != nil {
+		if err := g.render(outPath, "page", data); err != nil {
 			return fmt.Errorf("failed to render page: %w", err)
 		}
 	}
@@ -252,16 +280,25 @@ func (g *Generator) generateSearch(cfg Config) error {
 	}
 
 	nav := templateutil.BuildNavigation(modules)
+	links := make([]PageLink, 0, len(modules))
+	for _, doc := range modules {
+		links = append(links, PageLink{
+			Name:        doc.Path,
+			URL:         templateutil.PathToURL(templateutil.SanitizePath(doc.Path)),
+			Description: doc.Purpose,
+		})
+	}
 	data := PageData{
 		Title:       "Search",
 		ProjectName: cfg.ProjectName,
 		ProjectURL:  cfg.ProjectURL,
-		NavItems:    nav,
+		Navigation:  nav,
+		Components:  links,
 		LastUpdated: time.Now(),
 		Theme:       cfg.Theme,
 	}
 
-	return templateutil.RenderTemplate(filepath.Join(cfg.OutputDir, "search.html"), "search", data, embeddedTemplates)
+	return g.render(filepath.Join(cfg.OutputDir, "search.html"), "search", data)
 }
 
 func (g *Generator) copyAssets(cfg Config) error {
@@ -285,9 +322,8 @@ func (g *Generator) copyAssets(cfg Config) error {
 
 	for _, asset := range assetFiles {
 		// Read asset from embedded files
-		data, err := embeddedTemplates.ReadFile(asset)
+		data, err := embeddedTemplates.ReadFile("templates/" + asset)
 		if err != nil {
-			log.Printf("Failed to read embedded asset %s: %v", asset, err)
 			return fmt.Errorf("failed to read embedded asset %s: %w", asset, err)
 		}
 
@@ -297,11 +333,9 @@ func (g *Generator) copyAssets(cfg Config) error {
 
 		// Write the asset to the destination
 		if err := os.WriteFile(destPath, data, 0644); err != nil {
-			log.Printf("Failed to write asset to %s: %v", destPath, err)
 			return fmt.Errorf("failed to write asset to %s: %w", destPath, err)
 		}
-
-		log.Printf("Successfully copied asset to: %s", destPath)
+		g.progress.Wrote(destPath)
 	}
 
 	return nil
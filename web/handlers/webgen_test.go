@@ -3,9 +3,14 @@
 package handlers
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/rgehrsitz/AutoDoc/internal/progress"
 	"github.com/rgehrsitz/AutoDoc/internal/storage"
 	"github.com/rgehrsitz/AutoDoc/internal/testutil"
 )
@@ -69,3 +74,57 @@ func TestGenerator(t *testing.T) {
 	helper.AssertTemplateContains(rendered, "Example Package")
 	helper.AssertTemplateContains(rendered, "Test component description")
 }
+
+func TestGenerateReportsProgress(t *testing.T) {
+	store := NewMockStorage()
+	docs := []*storage.Document{
+		{ID: "arch1", Type: storage.TypeArchitecture, Path: "architecture.md", Content: "# Architecture\n\nLayers of the app."},
+		{ID: "mod1", Type: storage.TypeModule, Path: "main.go", Purpose: "Starts the app", Content: "# main.go\n\nStarts the app."},
+	}
+	for _, doc := range docs {
+		if err := store.SaveDocument(doc); err != nil {
+			t.Fatalf("Failed to save document: %v", err)
+		}
+	}
+
+	var stages, wrote []string
+	gen := NewGenerator(store)
+	gen.SetProgress(progress.NewTracker(progress.ReporterFunc(func(e progress.Event) {
+		switch e.Type {
+		case progress.EventStage:
+			stages = append(stages, e.Stage)
+		case progress.EventWrote:
+			wrote = append(wrote, e.File)
+		}
+	})))
+
+	dir := t.TempDir()
+	if err := gen.Generate(Config{OutputDir: dir, ProjectName: "app"}); err != nil {
+		t.Fatalf("Failed to generate wiki: %v", err)
+	}
+
+	if got := strings.Join(stages, ","); got != "index,architecture,modules,search,assets" {
+		t.Errorf("Expected every stage in order, got %s", got)
+	}
+	for _, name := range []string{"index.html", "architecture.html", "main.go.html", "search.html", "assets/css/light.css"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if !slices.Contains(wrote, path) {
+			t.Errorf("Expected %s to be reported as written, got %v", name, wrote)
+		}
+	}
+
+	page, err := os.ReadFile(filepath.Join(dir, "main.go.html"))
+	if err != nil {
+		t.Fatalf("Failed to read module page: %v", err)
+	}
+	if !strings.Contains(string(page), "Starts the app.") {
+		t.Errorf("Expected the module page to contain its content, got %s", page)
+	}
+	search, err := os.ReadFile(filepath.Join(dir, "search.html"))
+	if err != nil {
+		t.Fatalf("Failed to read search page: %v", err)
+	}
+	if !strings.Contains(string(search), `href="main.go.html"`) {
+		t.Errorf("Expected the search page to link main.go, got %s", search)
+	}
+}